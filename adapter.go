@@ -0,0 +1,53 @@
+package sat
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/jamesainslie/go-sat/inference"
+)
+
+// isAdapterWeights reports whether adapter names a LoRA weights file rather
+// than a pre-merged model variant.
+func isAdapterWeights(adapter string) bool {
+	return strings.EqualFold(filepath.Ext(adapter), ".safetensors")
+}
+
+// variantPath returns the path of the model variant name exported next to
+// modelPath, e.g. "sat/model.legal.onnx" for "sat/model.onnx" and "legal".
+func variantPath(modelPath, name string) string {
+	ext := filepath.Ext(modelPath)
+	return strings.TrimSuffix(modelPath, ext) + "." + name + ext
+}
+
+// loadAdapter reads the LoRA weights at weightsPath, and the
+// adapter_config.json at configPath if it exists, with readFile. It records
+// the adapter in cfg for the native backend and in src for the logit cache.
+func loadAdapter(cfg *config, src *modelSource, weightsPath, configPath string, readFile func(string) ([]byte, error)) error {
+	if cfg.native == nil {
+		return fmt.Errorf("%w: %s: LoRA weights need the native backend", ErrInvalidAdapter, weightsPath)
+	}
+
+	weights, err := readFile(weightsPath)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidAdapter, err)
+	}
+	adapterConfig, err := readFile(configPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %w", ErrInvalidAdapter, err)
+	}
+
+	adapter, err := inference.LoadAdapterFromBytes(weights, adapterConfig)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidAdapter, err)
+	}
+
+	native := *cfg.native
+	native.Adapter = adapter
+	cfg.native = &native
+	src.adapterID = dataIdentity(weights) + ":" + dataIdentity(adapterConfig)
+	return nil
+}
@@ -0,0 +1,70 @@
+package sat
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jamesainslie/go-sat/inference"
+)
+
+func TestVariantPath(t *testing.T) {
+	tests := []struct {
+		model, name, want string
+	}{
+		{"sat/model.onnx", "legal", "sat/model.legal.onnx"},
+		{"model_optimized.onnx", "lyrics", "model_optimized.lyrics.onnx"},
+		{"weights", "verse", "weights.verse"},
+	}
+	for _, tc := range tests {
+		if got := variantPath(tc.model, tc.name); got != tc.want {
+			t.Errorf("variantPath(%q, %q) = %q, want %q", tc.model, tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestWithAdapter_Errors(t *testing.T) {
+	dir := t.TempDir()
+	model := filepath.Join(dir, "model.onnx")
+	adapter := filepath.Join(dir, "adapter_model.safetensors")
+	for _, path := range []string{model, adapter} {
+		if err := os.WriteFile(path, []byte("not a model"), 0o600); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+	tok := writeTestTokenizer(t)
+	tokenizerData, err := os.ReadFile(tok)
+	if err != nil {
+		t.Fatalf("read tokenizer: %v", err)
+	}
+	native := WithNativeBackend(inference.NativeConfig{})
+
+	if _, err := New(model, tok, WithAdapter(adapter)); !errors.Is(err, ErrInvalidAdapter) {
+		t.Errorf("weights with ONNX Runtime: expected ErrInvalidAdapter, got: %v", err)
+	}
+	if _, err := New(model, tok, native, WithAdapter(adapter)); !errors.Is(err, ErrInvalidAdapter) {
+		t.Errorf("malformed weights: expected ErrInvalidAdapter, got: %v", err)
+	}
+	if _, err := New(model, tok, native, WithAdapter(filepath.Join(dir, "missing.safetensors"))); !errors.Is(err, ErrInvalidAdapter) {
+		t.Errorf("missing weights: expected ErrInvalidAdapter, got: %v", err)
+	}
+	if _, err := New(model, tok, WithAdapter("legal")); !errors.Is(err, ErrModelNotFound) {
+		t.Errorf("missing variant: expected ErrModelNotFound, got: %v", err)
+	}
+	if _, err := NewFromBytes([]byte("model"), tokenizerData, WithAdapter("legal")); !errors.Is(err, ErrInvalidAdapter) {
+		t.Errorf("variant from bytes: expected ErrInvalidAdapter, got: %v", err)
+	}
+
+	fsys := fstest.MapFS{
+		"model.onnx":      {Data: []byte("model")},
+		"tokenizer.model": {Data: tokenizerData},
+	}
+	if _, err := NewFromFS(fsys, "model.onnx", "tokenizer.model", WithAdapter("legal")); !errors.Is(err, ErrModelNotFound) {
+		t.Errorf("missing variant in FS: expected ErrModelNotFound, got: %v", err)
+	}
+	if _, err := NewFromFS(fsys, "model.onnx", "tokenizer.model", native, WithAdapter("adapter.safetensors")); !errors.Is(err, ErrInvalidAdapter) {
+		t.Errorf("missing weights in FS: expected ErrInvalidAdapter, got: %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package sat
+
+import (
+	"context"
+	"math"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jamesainslie/go-sat/tokenizer"
+)
+
+// adaptiveKind selects how the threshold is chosen per document.
+type adaptiveKind int
+
+const (
+	adaptiveNone adaptiveKind = iota
+	adaptiveCharsPerSentence
+	adaptiveProbabilityMass
+)
+
+// adaptiveRule chooses the threshold of each document from its expected
+// number of sentences.
+type adaptiveRule struct {
+	kind             adaptiveKind
+	charsPerSentence int
+}
+
+// sentenceCount returns the number of sentences the rule expects in text.
+func (r adaptiveRule) sentenceCount(text string, probs []float32) int {
+	switch r.kind {
+	case adaptiveCharsPerSentence:
+		chars := utf8.RuneCountInString(strings.TrimSpace(text))
+		return int(math.Round(float64(chars) / float64(r.charsPerSentence)))
+	case adaptiveProbabilityMass:
+		// Each sentence, including the last, ends at one boundary, so the
+		// summed probabilities estimate the number of sentences
+		var mass float64
+		for _, p := range probs {
+			mass += float64(p)
+		}
+		return int(math.Round(mass))
+	default:
+		return 0
+	}
+}
+
+// documentSplits returns the token indices after which text is split into
+// sentences with the configured threshold or adaptive rule. An adaptive rule
+// keeps the most probable boundaries, as many as it expects sentences less
+// one; with length limits, its threshold is the probability just below the
+// last of them.
+func (c callConfig) documentSplits(text string, tokens []tokenizer.TokenInfo, probs []float32) []int {
+	if c.adaptive.kind == adaptiveNone {
+		return c.sentenceSplits(text, tokens, probs, c.threshold)
+	}
+
+	k := c.adaptive.sentenceCount(text, probs) - 1
+	if !c.lengths.enabled() {
+		return topSplits(text, tokens, probs, k)
+	}
+
+	threshold := float32(1) // nothing passes
+	if splits := topSplits(text, tokens, probs, k); len(splits) > 0 {
+		lowest := probs[splits[0]]
+		for _, i := range splits {
+			lowest = min(lowest, probs[i])
+		}
+		threshold = math.Nextafter32(lowest, float32(math.Inf(-1)))
+	}
+	return c.lengthSplits(text, tokens, probs, threshold)
+}
+
+// SegmentN splits text into exactly n sentences by splitting after the n-1
+// tokens with the highest boundary probability, regardless of the threshold.
+// Fewer sentences are returned if text has fewer than n-1 places to split;
+// n < 1 is treated as 1. Sentences are as described for SegmentDetailed, and
+// length limits set with WithMinLength and WithMaxLength do not apply.
+func (s *Segmenter) SegmentN(ctx context.Context, text string, n int) ([]Sentence, error) {
+	tokens, probs, err := s.tokenProbabilities(ctx, text)
+	if err != nil || len(tokens) == 0 {
+		return nil, err
+	}
+
+	return buildSentences(text, tokens, probs, topSplits(text, tokens, probs, n-1)), nil
+}
+
+// topSplits returns, in ascending order, the k split candidates with the
+// highest probability. Ties go to the earlier token.
+func topSplits(text string, tokens []tokenizer.TokenInfo, probs []float32, k int) []int {
+	candidates := splitCandidates(text, tokens, probs)
+	if k <= 0 {
+		return nil
+	}
+
+	slices.SortStableFunc(candidates, func(a, b int) int {
+		switch {
+		case probs[a] > probs[b]:
+			return -1
+		case probs[a] < probs[b]:
+			return 1
+		default:
+			return 0
+		}
+	})
+	splits := candidates[:min(k, len(candidates))]
+	slices.Sort(splits)
+	return splits
+}
+
+// splitCandidates returns the token indices after which a split produces a
+// new, non-empty sentence: one per distinct sentence end strictly inside
+// text (see splitEnd), choosing the most probable token among those ending a
+// sentence at the same offset.
+func splitCandidates(text string, tokens []tokenizer.TokenInfo, probs []float32) []int {
+	var candidates []int
+	last := 0
+	for i := range min(len(tokens), len(probs)) {
+		end := splitEnd(text, tokens[i])
+		if end <= 0 || end >= len(text) {
+			continue
+		}
+		if end == last && len(candidates) > 0 {
+			if prev := candidates[len(candidates)-1]; probs[i] > probs[prev] {
+				candidates[len(candidates)-1] = i
+			}
+			continue
+		}
+		if end < last {
+			continue
+		}
+		candidates = append(candidates, i)
+		last = end
+	}
+	return candidates
+}
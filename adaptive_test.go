@@ -0,0 +1,151 @@
+package sat
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/jamesainslie/go-sat/sattest"
+)
+
+func TestTopSplits(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	text := "One. Two! Three? Four"
+	tokens := tok.Encode(text)
+	probs := make([]float32, len(tokens))
+	var punct []int
+	for i, tk := range tokens {
+		switch tk.Text {
+		case ".":
+			probs[i] = 0.3
+		case "!":
+			probs[i] = 0.9
+		case "?":
+			probs[i] = 0.6
+		}
+		if probs[i] > 0 {
+			punct = append(punct, i)
+		}
+	}
+	probs[len(probs)-1] = 1 // the end of the text is never a candidate
+
+	tests := []struct {
+		k    int
+		want []int
+	}{
+		{0, nil},
+		{1, []int{punct[1]}},
+		{2, []int{punct[1], punct[2]}},
+		{3, punct},
+	}
+	for _, tc := range tests {
+		if got := topSplits(text, tokens, probs, tc.k); !slices.Equal(got, tc.want) {
+			t.Errorf("topSplits(k=%d) = %v, want %v", tc.k, got, tc.want)
+		}
+	}
+
+	// More splits than candidates: every token end inside the text
+	if got := topSplits(text, tokens, probs, 100); len(got) != len(tokens)-1 {
+		t.Errorf("topSplits(k=100) returned %d splits, want %d", len(got), len(tokens)-1)
+	}
+}
+
+func TestSegmentN(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	backend := sattest.NewSentenceBackend(tok)
+	seg, err := NewWithBackend(backend, tok)
+	if err != nil {
+		t.Fatalf("NewWithBackend() failed: %v", err)
+	}
+	defer func() { _ = seg.Close() }()
+
+	ctx := context.Background()
+	text := "One. Two. Three. Four"
+
+	for _, n := range []int{0, 1, 2, 4, 6} {
+		sentences, err := seg.SegmentN(ctx, text, n)
+		if err != nil {
+			t.Fatalf("SegmentN(%d) failed: %v", n, err)
+		}
+		if want := max(n, 1); len(sentences) != want {
+			t.Errorf("SegmentN(%d) returned %d sentences, want %d", n, len(sentences), want)
+		}
+	}
+
+	sentences, err := seg.SegmentN(ctx, text, 4)
+	if err != nil {
+		t.Fatalf("SegmentN failed: %v", err)
+	}
+	var got []string
+	for _, sent := range sentences {
+		got = append(got, sent.Text)
+	}
+	if want := []string{"One.", " Two.", " Three.", " Four"}; !slices.Equal(got, want) {
+		t.Errorf("SegmentN(4) = %q, want %q", got, want)
+	}
+
+	if sentences, err := seg.SegmentN(ctx, "", 3); err != nil || sentences != nil {
+		t.Errorf("SegmentN(\"\") = %v, %v; want nil, nil", sentences, err)
+	}
+}
+
+func TestAdaptiveThreshold(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	ctx := context.Background()
+	text := "One. Two. Three. Four."
+
+	tests := []struct {
+		name string
+		opts []Option
+		want int
+	}{
+		// 22 characters at 11 per sentence: the single most probable split
+		{"chars per sentence", []Option{WithCharsPerSentence(11)}, 2},
+		{"chars per sentence, long", []Option{WithCharsPerSentence(1000)}, 1},
+		// Four boundaries at about 0.73 each sum to about 3
+		{"probability mass", []Option{WithProbabilityMass()}, 3},
+		{"threshold replaces rule", []Option{WithProbabilityMass(), WithThreshold(0.5)}, 4},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			backend := sattest.NewSentenceBackend(tok)
+			backend.BoundaryLogit = 1 // sigmoid(1) ≈ 0.73
+			seg, err := NewWithBackend(backend, tok, tc.opts...)
+			if err != nil {
+				t.Fatalf("NewWithBackend() failed: %v", err)
+			}
+			defer func() { _ = seg.Close() }()
+
+			got, err := seg.Segment(ctx, text)
+			if err != nil {
+				t.Fatalf("Segment failed: %v", err)
+			}
+			if len(got) != tc.want {
+				t.Errorf("Segment returned %d sentences, want %d: %q", len(got), tc.want, got)
+			}
+
+			batch, err := seg.SegmentBatch(ctx, []string{text})
+			if err != nil {
+				t.Fatalf("SegmentBatch failed: %v", err)
+			}
+			if !slices.Equal(batch[0], got) {
+				t.Errorf("SegmentBatch = %q, Segment = %q", batch[0], got)
+			}
+		})
+	}
+}
+
+func TestSegmentN_StraddlingTokens(t *testing.T) {
+	seg := newCJKSegmenter(t)
+
+	// The last token, "。我们", ends the text but holds a sentence end
+	got, err := seg.SegmentN(context.Background(), "今天很好。我们", 2)
+	if err != nil {
+		t.Fatalf("SegmentN() failed: %v", err)
+	}
+	want := []string{"今天很好。", "我们"}
+	if texts := sentenceTexts(got); !slices.Equal(texts, want) {
+		t.Errorf("SegmentN() = %q, want %q", texts, want)
+	}
+}
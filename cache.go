@@ -0,0 +1,194 @@
+package sat
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/jamesainslie/go-sat/tokenizer"
+)
+
+// cacheEntryOverhead approximates the memory used by a cache entry besides
+// its logits: the key, list element and map entry.
+const cacheEntryOverhead = 128
+
+// LogitCache is an LRU cache of model outputs keyed by a hash of the text
+// and the identity of the model that produced them. Logits do not depend on
+// the threshold, length limits or other per-call settings, so repeated calls
+// on the same text, such as a threshold sweep, run the model once.
+//
+// A LogitCache is bounded by the approximate number of bytes its entries
+// occupy. It is safe for concurrent use and may be shared by several
+// Segmenters; entries from different models, or from the same model with
+// different chunking or backend settings, never collide.
+type LogitCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	bytes    int64
+	order    *list.List // front is most recently used
+	entries  map[[sha256.Size]byte]*list.Element
+	hits     int64
+	misses   int64
+}
+
+type cacheEntry struct {
+	key    [sha256.Size]byte
+	logits []float32
+}
+
+// CacheStats reports the usage of a LogitCache.
+type CacheStats struct {
+	Entries int
+	Bytes   int64
+	Hits    int64
+	Misses  int64
+}
+
+// NewLogitCache returns an empty cache holding up to maxBytes of logits.
+func NewLogitCache(maxBytes int64) *LogitCache {
+	return &LogitCache{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[[sha256.Size]byte]*list.Element),
+	}
+}
+
+// Stats returns the cache's current size and hit counts.
+func (c *LogitCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheStats{
+		Entries: c.order.Len(),
+		Bytes:   c.bytes,
+		Hits:    c.hits,
+		Misses:  c.misses,
+	}
+}
+
+// get returns the cached logits for key. The result must not be modified.
+func (c *LogitCache) get(key [sha256.Size]byte) ([]float32, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.order.MoveToFront(elem)
+	return elem.Value.(*cacheEntry).logits, true
+}
+
+// put stores logits under key, evicting the least recently used entries to
+// stay within the size limit. Logits larger than the whole cache are not
+// stored.
+func (c *LogitCache) put(key [sha256.Size]byte, logits []float32) {
+	size := entrySize(logits)
+	if size > c.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, logits: logits})
+	c.bytes += size
+	for c.bytes > c.maxBytes {
+		oldest := c.order.Back()
+		entry := oldest.Value.(*cacheEntry)
+		c.order.Remove(oldest)
+		delete(c.entries, entry.key)
+		c.bytes -= entrySize(entry.logits)
+	}
+}
+
+// entrySize returns the approximate memory used by an entry holding logits.
+func entrySize(logits []float32) int64 {
+	return int64(4*len(logits)) + cacheEntryOverhead
+}
+
+// fileIdentity identifies a model file by its absolute path, size and
+// modification time, without reading it.
+func fileIdentity(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "file:" + path
+	}
+	return fmt.Sprintf("file:%s:%d:%d", path, info.Size(), info.ModTime().UnixNano())
+}
+
+// dataIdentity identifies model data by its SHA-256 hash.
+func dataIdentity(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// cacheNamespace returns the hash that separates this Segmenter's cache
+// entries from those of other models and chunking settings. identity names
+// the model and tokenizer.
+func (s *Segmenter) cacheNamespace(identity string) [sha256.Size]byte {
+	return sha256.Sum256(fmt.Appendf(nil, "%s\x00%d:%d:%d:%d:%t",
+		identity, s.numLabels, s.chunkSize, s.chunkStride, s.stitching, s.specialTokens))
+}
+
+// cacheKey returns the cache key of text.
+func (s *Segmenter) cacheKey(text string) [sha256.Size]byte {
+	h := sha256.New()
+	h.Write(s.cacheNS[:])
+	h.Write([]byte(text))
+	var key [sha256.Size]byte
+	h.Sum(key[:0])
+	return key
+}
+
+// textLogits returns the logits of every label for each text, laid out as by
+// getLogitsBatch, where seqs holds the tokens of each text. With a cache,
+// only texts not found in it are inferred. The results must not be modified.
+func (s *Segmenter) textLogits(ctx context.Context, texts []string, seqs [][]tokenizer.TokenInfo) ([][]float32, error) {
+	if s.cache == nil {
+		return s.getLogitsBatch(ctx, seqs)
+	}
+
+	logits := make([][]float32, len(texts))
+	keys := make([][sha256.Size]byte, len(texts))
+	var missing []int
+	for i, text := range texts {
+		keys[i] = s.cacheKey(text)
+		if cached, ok := s.cache.get(keys[i]); ok {
+			logits[i] = cached
+		} else {
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) == 0 {
+		return logits, nil
+	}
+
+	missingSeqs := make([][]tokenizer.TokenInfo, len(missing))
+	for j, i := range missing {
+		missingSeqs[j] = seqs[i]
+	}
+	inferred, err := s.getLogitsBatch(ctx, missingSeqs)
+	if err != nil {
+		return nil, err
+	}
+	for j, i := range missing {
+		logits[i] = inferred[j]
+		s.cache.put(keys[i], inferred[j])
+	}
+	return logits, nil
+}
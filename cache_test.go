@@ -0,0 +1,185 @@
+package sat
+
+import (
+	"context"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/jamesainslie/go-sat/inference"
+	"github.com/jamesainslie/go-sat/sattest"
+)
+
+func TestLogitCache_LRU(t *testing.T) {
+	logits := make([]float32, 8)
+	size := entrySize(logits)
+	c := NewLogitCache(2 * size)
+
+	key := func(s string) [sha256.Size]byte { return sha256.Sum256([]byte(s)) }
+	c.put(key("a"), logits)
+	c.put(key("b"), logits)
+	if _, ok := c.get(key("a")); !ok { // a is now most recently used
+		t.Fatal("a missing before eviction")
+	}
+	c.put(key("c"), logits)
+
+	if _, ok := c.get(key("b")); ok {
+		t.Error("b was not evicted")
+	}
+	for _, k := range []string{"a", "c"} {
+		if _, ok := c.get(key(k)); !ok {
+			t.Errorf("%s was evicted", k)
+		}
+	}
+
+	stats := c.Stats()
+	want := CacheStats{Entries: 2, Bytes: 2 * size, Hits: 3, Misses: 1}
+	if stats != want {
+		t.Errorf("Stats() = %+v, want %+v", stats, want)
+	}
+
+	// Larger than the whole cache
+	c.put(key("big"), make([]float32, 100))
+	if _, ok := c.get(key("big")); ok {
+		t.Error("oversized entry was stored")
+	}
+}
+
+func TestSegment_LogitCache(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	ctx := context.Background()
+	cache := NewLogitCache(1 << 20)
+
+	backend := sattest.NewSentenceBackend(tok)
+	backend.BoundaryLogit = 1 // sigmoid(1) ≈ 0.73
+	seg, err := NewWithBackend(backend, tok, WithLogitCache(cache))
+	if err != nil {
+		t.Fatalf("NewWithBackend() failed: %v", err)
+	}
+	defer func() { _ = seg.Close() }()
+
+	text := "One. Two. Three."
+	first, err := seg.Segment(ctx, text)
+	if err != nil {
+		t.Fatalf("Segment failed: %v", err)
+	}
+
+	// Other thresholds reuse the cached logits
+	if got, err := seg.Segment(ctx, text, CallThreshold(0.9)); err != nil || len(got) != 1 {
+		t.Errorf("Segment with threshold 0.9 = %q, %v; want one sentence", got, err)
+	}
+	if complete, _, err := seg.IsComplete(ctx, text); err != nil || !complete {
+		t.Errorf("IsComplete = %v, %v; want true", complete, err)
+	}
+	again, err := seg.Segment(ctx, text)
+	if err != nil {
+		t.Fatalf("Segment failed: %v", err)
+	}
+	if !slices.Equal(first, again) {
+		t.Errorf("cached result %q differs from %q", again, first)
+	}
+	if backend.Calls() != 1 {
+		t.Errorf("backend called %d times, want 1", backend.Calls())
+	}
+
+	// A batch only infers the texts not yet cached
+	results, err := seg.SegmentBatch(ctx, []string{text, "Four. Five."})
+	if err != nil {
+		t.Fatalf("SegmentBatch failed: %v", err)
+	}
+	if !slices.Equal(results[0], first) || len(results[1]) != 2 {
+		t.Errorf("SegmentBatch = %q", results)
+	}
+	if backend.Calls() != 2 || backend.Rows() != 2 {
+		t.Errorf("backend inferred %d rows in %d calls, want 2 in 2", backend.Rows(), backend.Calls())
+	}
+
+	stats := cache.Stats()
+	if stats.Entries != 2 || stats.Hits != 4 || stats.Misses != 2 {
+		t.Errorf("Stats() = %+v, want 2 entries, 4 hits, 2 misses", stats)
+	}
+}
+
+func TestSegment_LogitCacheShared(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	ctx := context.Background()
+	cache := NewLogitCache(1 << 20)
+	text := "One. Two."
+
+	// Two models sharing a cache must not see each other's logits
+	var results [2][]string
+	for i, logit := range []float32{10, -10} {
+		backend := sattest.NewSentenceBackend(tok)
+		backend.BoundaryLogit = logit
+		seg, err := NewWithBackend(backend, tok, WithLogitCache(cache))
+		if err != nil {
+			t.Fatalf("NewWithBackend() failed: %v", err)
+		}
+		results[i], err = seg.Segment(ctx, text)
+		if err != nil {
+			t.Fatalf("Segment failed: %v", err)
+		}
+		if backend.Calls() != 1 {
+			t.Errorf("model %d: backend called %d times, want 1", i, backend.Calls())
+		}
+	}
+	if len(results[0]) != 2 || len(results[1]) != 1 {
+		t.Errorf("results = %q, want 2 and 1 sentences", results)
+	}
+}
+
+func TestModelSourceIdentity(t *testing.T) {
+	cfg := defaultConfig()
+	a := modelSource{data: []byte("model a"), tokenizerID: "tok"}
+	b := modelSource{data: []byte("model b"), tokenizerID: "tok"}
+
+	if a.identity(cfg) == b.identity(cfg) {
+		t.Error("different model data share an identity")
+	}
+	if a.identity(cfg) != (modelSource{data: []byte("model a"), tokenizerID: "tok"}).identity(cfg) {
+		t.Error("identical model data have different identities")
+	}
+	if a.identity(cfg) == (modelSource{data: []byte("model a"), tokenizerID: "other"}).identity(cfg) {
+		t.Error("different tokenizers share an identity")
+	}
+}
+
+func TestModelSourceIdentity_Backend(t *testing.T) {
+	src := modelSource{data: []byte("model"), tokenizerID: "tok"}
+	optimized := filepath.Join(t.TempDir(), "optimized.onnx")
+	if err := os.WriteFile(optimized, []byte("optimized"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	configs := map[string][]Option{
+		"onnx":               nil,
+		"graph optimization": {WithGraphOptimizationLevel(inference.GraphOptimizationBasic)},
+		"preoptimized model": {WithPreoptimizedModel(optimized)},
+		"native":             {WithNativeBackend(inference.NativeConfig{})},
+		"heads":              {WithNativeBackend(inference.NativeConfig{NumHeads: 4})},
+		"layer norm eps":     {WithNativeBackend(inference.NativeConfig{LayerNormEps: 1e-6})},
+		"lookahead":          {WithNativeBackend(inference.NativeConfig{Lookahead: 48})},
+	}
+
+	seen := make(map[string]string)
+	for name, opts := range configs {
+		cfg := defaultConfig()
+		for _, opt := range opts {
+			opt(&cfg)
+		}
+		id := src.identity(cfg)
+		if other, ok := seen[id]; ok {
+			t.Errorf("%s and %s share an identity", name, other)
+		}
+		seen[id] = name
+	}
+
+	// Scheduling settings do not change the logits
+	cfg := defaultConfig()
+	WithIntraOpThreads(2)(&cfg)
+	if src.identity(cfg) != src.identity(defaultConfig()) {
+		t.Error("thread count changes the identity")
+	}
+}
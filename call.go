@@ -0,0 +1,125 @@
+package sat
+
+import "fmt"
+
+// CallOption overrides the Segmenter's configuration for a single call, so
+// one Segmenter, and its loaded model, can serve callers that need different
+// thresholds or limits.
+type CallOption func(*callConfig)
+
+// callConfig holds the settings that may be changed per call. The
+// Segmenter's configuration provides the defaults.
+type callConfig struct {
+	threshold            float32
+	adaptive             adaptiveRule
+	lengths              lengthConstraint
+	stripWhitespace      bool
+	dropEmpty            bool
+	attachClosing        bool
+	skipWhitespaceTokens bool
+	language             string
+	detectLanguage       bool
+}
+
+// callConfig returns the Segmenter's defaults with opts applied.
+func (s *Segmenter) callConfig(opts []CallOption) (callConfig, error) {
+	cc := s.defaults
+	for _, opt := range opts {
+		opt(&cc)
+	}
+	if err := cc.lengths.validate(); err != nil {
+		return callConfig{}, err
+	}
+	return cc, nil
+}
+
+// validate reports an error if the minimum exceeds the maximum.
+func (lc lengthConstraint) validate() error {
+	if lc.max > 0 && lc.min > lc.max {
+		return fmt.Errorf("%w: minimum %d exceeds maximum %d", ErrInvalidLength, lc.min, lc.max)
+	}
+	return nil
+}
+
+// CallThreshold sets the boundary threshold for one call, replacing the
+// threshold or adaptive rule of the Segmenter.
+func CallThreshold(t float32) CallOption {
+	return func(c *callConfig) {
+		c.threshold = t
+		c.adaptive = adaptiveRule{}
+	}
+}
+
+// CallCharsPerSentence is the per-call form of WithCharsPerSentence.
+func CallCharsPerSentence(n int) CallOption {
+	return func(c *callConfig) {
+		if n > 0 {
+			c.adaptive = adaptiveRule{kind: adaptiveCharsPerSentence, charsPerSentence: n}
+		}
+	}
+}
+
+// CallProbabilityMass is the per-call form of WithProbabilityMass.
+func CallProbabilityMass() CallOption {
+	return func(c *callConfig) {
+		c.adaptive = adaptiveRule{kind: adaptiveProbabilityMass}
+	}
+}
+
+// CallMinLength is the per-call form of WithMinLength.
+func CallMinLength(n int) CallOption {
+	return func(c *callConfig) {
+		c.lengths.min = max(n, 0)
+	}
+}
+
+// CallMaxLength is the per-call form of WithMaxLength.
+func CallMaxLength(n int) CallOption {
+	return func(c *callConfig) {
+		c.lengths.max = max(n, 0)
+	}
+}
+
+// CallLengthUnit is the per-call form of WithLengthUnit.
+func CallLengthUnit(unit LengthUnit) CallOption {
+	return func(c *callConfig) {
+		c.lengths.unit = unit
+	}
+}
+
+// CallStripWhitespace is the per-call form of WithStripWhitespace.
+func CallStripWhitespace(strip bool) CallOption {
+	return func(c *callConfig) {
+		c.stripWhitespace = strip
+	}
+}
+
+// CallDropEmpty is the per-call form of WithDropEmpty.
+func CallDropEmpty(drop bool) CallOption {
+	return func(c *callConfig) {
+		c.dropEmpty = drop
+	}
+}
+
+// CallAttachClosing is the per-call form of WithAttachClosing.
+func CallAttachClosing(attach bool) CallOption {
+	return func(c *callConfig) {
+		c.attachClosing = attach
+	}
+}
+
+// CallSkipWhitespaceTokens is the per-call form of WithSkipWhitespaceTokens.
+func CallSkipWhitespaceTokens(skip bool) CallOption {
+	return func(c *callConfig) {
+		c.skipWhitespaceTokens = skip
+	}
+}
+
+// sentenceTexts returns the text of each sentence.
+func sentenceTexts(sentences []Sentence) []string {
+	texts := make([]string, len(sentences))
+	for i, sent := range sentences {
+		texts[i] = sent.Text
+	}
+	return texts
+}
@@ -0,0 +1,152 @@
+package sat
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/jamesainslie/go-sat/sattest"
+)
+
+func TestCallOptions(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	ctx := context.Background()
+	text := "One. Two. Three four five six seven. Eight "
+
+	backend := sattest.NewSentenceBackend(tok)
+	backend.BoundaryLogit = 1 // sigmoid(1) ≈ 0.73
+	seg, err := NewWithBackend(backend, tok)
+	if err != nil {
+		t.Fatalf("NewWithBackend() failed: %v", err)
+	}
+	defer func() { _ = seg.Close() }()
+
+	tests := []struct {
+		name string
+		opts []CallOption
+		want []string
+	}{
+		{"defaults", nil, []string{"One.", " Two.", " Three four five six seven.", " Eight "}},
+		{"threshold", []CallOption{CallThreshold(0.9)}, []string{text}},
+		{"strip whitespace", []CallOption{CallStripWhitespace(true)}, []string{"One.", "Two.", "Three four five six seven.", "Eight"}},
+		{"min length", []CallOption{CallMinLength(9)}, []string{"One. Two.", " Three four five six seven. Eight "}},
+		{"max length in tokens", []CallOption{CallMaxLength(8), CallLengthUnit(LengthTokens), CallStripWhitespace(true)}, nil},
+		// Equally probable boundaries: the earliest wins
+		{"sentence count", []CallOption{CallCharsPerSentence(20)}, []string{"One.", " Two. Three four five six seven. Eight "}},
+		{"threshold replaces rule", []CallOption{CallProbabilityMass(), CallThreshold(0.5)}, []string{"One.", " Two.", " Three four five six seven.", " Eight "}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := seg.Segment(ctx, text, tc.opts...)
+			if err != nil {
+				t.Fatalf("Segment failed: %v", err)
+			}
+			if tc.want != nil && !slices.Equal(got, tc.want) {
+				t.Errorf("Segment = %q, want %q", got, tc.want)
+			}
+
+			batch, err := seg.SegmentBatch(ctx, []string{text}, tc.opts...)
+			if err != nil {
+				t.Fatalf("SegmentBatch failed: %v", err)
+			}
+			if !slices.Equal(batch[0], got) {
+				t.Errorf("SegmentBatch = %q, Segment = %q", batch[0], got)
+			}
+		})
+	}
+
+	// Options do not change the Segmenter's defaults
+	got, err := seg.Segment(ctx, text)
+	if err != nil {
+		t.Fatalf("Segment failed: %v", err)
+	}
+	if len(got) != 4 {
+		t.Errorf("Segment after call options returned %d sentences, want 4", len(got))
+	}
+}
+
+func TestCallOptions_MaxLength(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	seg, err := NewWithBackend(sattest.NewSentenceBackend(tok), tok)
+	if err != nil {
+		t.Fatalf("NewWithBackend() failed: %v", err)
+	}
+	defer func() { _ = seg.Close() }()
+
+	sentences, err := seg.SegmentDetailed(context.Background(), "One two three four five six seven eight nine ten",
+		CallMaxLength(8), CallLengthUnit(LengthTokens))
+	if err != nil {
+		t.Fatalf("SegmentDetailed failed: %v", err)
+	}
+	if len(sentences) < 2 {
+		t.Fatalf("expected several sentences, got %d", len(sentences))
+	}
+	for _, sent := range sentences {
+		if n := sent.TokenEnd - sent.TokenStart; n > 8 {
+			t.Errorf("sentence %q has %d tokens, want at most 8", sent.Text, n)
+		}
+	}
+}
+
+func TestSegmentWithBoundaries_Strip(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	seg, err := NewWithBackend(sattest.NewSentenceBackend(tok), tok)
+	if err != nil {
+		t.Fatalf("NewWithBackend() failed: %v", err)
+	}
+	defer func() { _ = seg.Close() }()
+
+	text := "Hi. Yo.  "
+	sentences, boundaries, err := seg.SegmentWithBoundaries(context.Background(), text, CallStripWhitespace(true))
+	if err != nil {
+		t.Fatalf("SegmentWithBoundaries failed: %v", err)
+	}
+	if want := []string{"Hi.", "Yo."}; !slices.Equal(sentences, want) {
+		t.Errorf("sentences = %q, want %q", sentences, want)
+	}
+	if want := []int{3, 7}; !slices.Equal(boundaries, want) {
+		t.Errorf("boundaries = %v, want %v", boundaries, want)
+	}
+}
+
+func TestCallOptions_Errors(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	seg, err := NewWithBackend(sattest.NewSentenceBackend(tok), tok)
+	if err != nil {
+		t.Fatalf("NewWithBackend() failed: %v", err)
+	}
+	defer func() { _ = seg.Close() }()
+
+	ctx := context.Background()
+	invalid := []CallOption{CallMinLength(10), CallMaxLength(5)}
+	if _, err := seg.Segment(ctx, "Hi.", invalid...); !errors.Is(err, ErrInvalidLength) {
+		t.Errorf("Segment error = %v, want ErrInvalidLength", err)
+	}
+	if _, err := seg.SegmentBatch(ctx, []string{"Hi."}, invalid...); !errors.Is(err, ErrInvalidLength) {
+		t.Errorf("SegmentBatch error = %v, want ErrInvalidLength", err)
+	}
+	if _, err := seg.SegmentParagraphs(ctx, "Hi.", invalid...); !errors.Is(err, ErrInvalidLength) {
+		t.Errorf("SegmentParagraphs error = %v, want ErrInvalidLength", err)
+	}
+}
+
+func TestIsComplete_CallThreshold(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	backend := sattest.NewSentenceBackend(tok)
+	backend.BoundaryLogit = 1 // sigmoid(1) ≈ 0.73
+	seg, err := NewWithBackend(backend, tok)
+	if err != nil {
+		t.Fatalf("NewWithBackend() failed: %v", err)
+	}
+	defer func() { _ = seg.Close() }()
+
+	ctx := context.Background()
+	if complete, _, err := seg.IsComplete(ctx, "Done."); err != nil || !complete {
+		t.Errorf("IsComplete = %v, %v; want true", complete, err)
+	}
+	if complete, _, err := seg.IsComplete(ctx, "Done.", CallThreshold(0.9)); err != nil || complete {
+		t.Errorf("IsComplete with threshold 0.9 = %v, %v; want false", complete, err)
+	}
+}
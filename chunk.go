@@ -0,0 +1,184 @@
+package sat
+
+import "math"
+
+// Stitching selects how logits are combined where the chunks of a long text
+// overlap.
+type Stitching int
+
+const (
+	// StitchMean averages the logits of every chunk covering a token with
+	// equal weight.
+	StitchMean Stitching = iota
+
+	// StitchWeighted averages the logits of every chunk covering a token,
+	// weighting each by the token's distance from the nearest chunk edge that
+	// cuts the text, so predictions made with more context count for more.
+	StitchWeighted
+
+	// StitchCenter takes each token's logits from the single chunk in which
+	// it lies farthest from a cutting edge, or the earlier of two equally
+	// distant chunks.
+	StitchCenter
+)
+
+// defaultChunkOverlap is the number of tokens consecutive chunks share unless
+// configured otherwise. It is reduced for short chunks so that the stride
+// stays at least three quarters of the chunk size.
+const defaultChunkOverlap = 64
+
+// numSpecialTokens is the number of positions taken by <s> and </s> when
+// chunks are framed with special tokens.
+const numSpecialTokens = 2
+
+// chunk is a window of tokens from one input sequence inferred in a model run.
+type chunk struct {
+	seq   int // index of the input sequence
+	start int // first token index (inclusive)
+	end   int // last token index (exclusive)
+}
+
+// chunkLayout returns the number of text tokens per chunk and the distance
+// between chunk starts for a backend accepting maxLen tokens.
+func chunkLayout(maxLen int, cfg config) (size, stride int) {
+	size = maxLen
+	if cfg.specialTokens {
+		size -= numSpecialTokens
+	}
+	if cfg.chunkSize > 0 {
+		size = min(size, cfg.chunkSize)
+	}
+	size = max(size, 1)
+
+	switch {
+	case cfg.chunkStride > 0:
+		stride = min(cfg.chunkStride, size)
+	case cfg.chunkOverlap >= 0:
+		stride = size - min(cfg.chunkOverlap, size-1)
+	default:
+		stride = size - min(defaultChunkOverlap, size/4)
+	}
+	return size, stride
+}
+
+// chunkRanges returns the [start, end) token windows used to infer a sequence
+// of n tokens. Windows are at most size long and start stride tokens apart,
+// so that consecutive windows overlap by size-stride tokens and boundary
+// detection works properly at chunk edges. A size or stride below 1 is
+// treated as 1, so that the windows always advance.
+func chunkRanges(n, size, stride int) [][2]int {
+	if n == 0 {
+		return nil
+	}
+	size, stride = max(size, 1), max(stride, 1)
+	if n <= size {
+		return [][2]int{{0, n}}
+	}
+
+	var ranges [][2]int
+	for start := 0; start < n; start += stride {
+		end := min(start+size, n)
+		ranges = append(ranges, [2]int{start, end})
+
+		// Stop if we've reached the end
+		if end >= n {
+			break
+		}
+	}
+	return ranges
+}
+
+// contextWeight returns 1 plus the distance of token pos from the nearest
+// edge of the window [start, end) that cuts a sequence of n tokens. Edges at
+// the start or end of the sequence do not cut it and are ignored.
+func contextWeight(pos, start, end, n int) float32 {
+	dist := math.MaxInt
+	if start > 0 {
+		dist = pos - start
+	}
+	if end < n {
+		dist = min(dist, end-1-pos)
+	}
+	if dist == math.MaxInt {
+		return 1
+	}
+	return float32(1 + dist)
+}
+
+// stitcher combines the logits of overlapping chunks.
+type stitcher struct {
+	mode    Stitching
+	labels  int
+	lengths []int
+	logits  [][]float32
+	weights [][]float32 // per token: summed weights, or best weight for StitchCenter
+	starts  [][]int     // per token: start of the chosen chunk, for StitchCenter
+}
+
+// newStitcher returns a stitcher for sequences of the given lengths with
+// labels logits per token.
+func newStitcher(mode Stitching, labels int, lengths []int) *stitcher {
+	st := &stitcher{
+		mode:    mode,
+		labels:  labels,
+		lengths: lengths,
+		logits:  make([][]float32, len(lengths)),
+		weights: make([][]float32, len(lengths)),
+	}
+	for i, n := range lengths {
+		st.logits[i] = make([]float32, n*labels)
+		st.weights[i] = make([]float32, n)
+	}
+	if mode == StitchCenter {
+		st.starts = make([][]int, len(lengths))
+		for i, n := range lengths {
+			st.starts[i] = make([]int, n)
+		}
+	}
+	return st
+}
+
+// add merges the logits inferred for chunk c. Chunks may be added in any
+// order; the result does not depend on it.
+func (st *stitcher) add(c chunk, logits []float32) {
+	n := st.lengths[c.seq]
+	for pos := c.start; pos < c.end; pos++ {
+		w := float32(1)
+		if st.mode != StitchMean {
+			w = contextWeight(pos, c.start, c.end, n)
+		}
+
+		src := logits[(pos-c.start)*st.labels : (pos-c.start+1)*st.labels]
+		dst := st.logits[c.seq][pos*st.labels : (pos+1)*st.labels]
+		if st.mode == StitchCenter {
+			// The chunk that starts first wins ties, whatever the order
+			// chunks are added in
+			best := st.weights[c.seq][pos]
+			if w > best || (w == best && c.start < st.starts[c.seq][pos]) {
+				copy(dst, src)
+				st.weights[c.seq][pos] = w
+				st.starts[c.seq][pos] = c.start
+			}
+			continue
+		}
+		for k, logit := range src {
+			dst[k] += w * logit
+		}
+		st.weights[c.seq][pos] += w
+	}
+}
+
+// result returns the stitched logits of every sequence.
+func (st *stitcher) result() [][]float32 {
+	if st.mode == StitchCenter {
+		return st.logits
+	}
+	for i, logits := range st.logits {
+		for j := range logits {
+			if w := st.weights[i][j/st.labels]; w > 0 && w != 1 {
+				logits[j] /= w
+			}
+		}
+	}
+	return st.logits
+}
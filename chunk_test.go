@@ -0,0 +1,220 @@
+package sat
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jamesainslie/go-sat/sattest"
+)
+
+func TestChunkRanges(t *testing.T) {
+	const size = 512
+
+	tests := []struct {
+		name   string
+		n      int
+		size   int
+		stride int
+		want   [][2]int
+	}{
+		{"empty", 0, size, 448, nil},
+		{"single chunk", 10, size, 448, [][2]int{{0, 10}}},
+		{"exactly size", size, size, 448, [][2]int{{0, size}}},
+		{"two chunks", size + 1, size, 448, [][2]int{{0, size}, {448, size + 1}}},
+		{"short size", 10, 8, 6, [][2]int{{0, 8}, {6, 10}}},
+		{"no overlap", 10, 4, 4, [][2]int{{0, 4}, {4, 8}, {8, 10}}},
+		{"zero stride", 3, 2, 0, [][2]int{{0, 2}, {1, 3}}},
+		{"zero size", 2, 0, 0, [][2]int{{0, 1}, {1, 2}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := chunkRanges(tc.n, tc.size, tc.stride)
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("chunkRanges(%d, %d, %d) = %v, want %v", tc.n, tc.size, tc.stride, got, tc.want)
+			}
+		})
+	}
+
+	// Every token must be covered for long inputs
+	n := 5000
+	covered := make([]bool, n)
+	for _, r := range chunkRanges(n, size, 448) {
+		if r[1]-r[0] > size {
+			t.Errorf("chunk %v longer than size", r)
+		}
+		for i := r[0]; i < r[1]; i++ {
+			covered[i] = true
+		}
+	}
+	if slices.Contains(covered, false) {
+		t.Error("chunkRanges left tokens uncovered")
+	}
+}
+
+func TestChunkLayout(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxLen     int
+		opts       []Option
+		wantSize   int
+		wantStride int
+	}{
+		{"default", 512, nil, 510, 446},
+		{"no special tokens", 512, []Option{WithSpecialTokens(false)}, 512, 448},
+		{"short backend", 18, nil, 16, 12},
+		{"chunk size", 512, []Option{WithChunkSize(100)}, 100, 75},
+		{"chunk size above limit", 512, []Option{WithChunkSize(1000)}, 510, 446},
+		{"overlap", 512, []Option{WithChunkOverlap(10)}, 510, 500},
+		{"zero overlap", 512, []Option{WithChunkOverlap(0)}, 510, 510},
+		{"overlap above size", 12, []Option{WithChunkOverlap(100)}, 10, 1},
+		{"stride", 512, []Option{WithChunkStride(128), WithChunkOverlap(10)}, 510, 128},
+		{"stride above size", 512, []Option{WithChunkStride(1000)}, 510, 510},
+		{"tiny backend", 2, nil, 1, 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := defaultConfig()
+			for _, opt := range tc.opts {
+				opt(&cfg)
+			}
+			size, stride := chunkLayout(tc.maxLen, cfg)
+			if size != tc.wantSize || stride != tc.wantStride {
+				t.Errorf("chunkLayout(%d) = %d, %d; want %d, %d", tc.maxLen, size, stride, tc.wantSize, tc.wantStride)
+			}
+		})
+	}
+}
+
+func TestStitcher(t *testing.T) {
+	// Two chunks of a 6-token sequence overlapping on tokens 2 and 3
+	first := chunk{seq: 0, start: 0, end: 4}
+	second := chunk{seq: 0, start: 2, end: 6}
+	firstLogits := []float32{1, 1, 1, 1}
+	secondLogits := []float32{5, 5, 5, 5}
+
+	tests := []struct {
+		mode Stitching
+		want []float32
+	}{
+		// Token 2 is 1 from the first chunk's cut edge and 0 from the
+		// second's; token 3 the reverse.
+		{StitchMean, []float32{1, 1, 3, 3, 5, 5}},
+		{StitchWeighted, []float32{1, 1, 7.0 / 3, 11.0 / 3, 5, 5}},
+		{StitchCenter, []float32{1, 1, 1, 5, 5, 5}},
+	}
+
+	for _, tc := range tests {
+		st := newStitcher(tc.mode, 1, []int{6})
+		st.add(first, firstLogits)
+		st.add(second, secondLogits)
+		got := st.result()[0]
+		for i := range tc.want {
+			if diff := got[i] - tc.want[i]; diff > 1e-5 || diff < -1e-5 {
+				t.Errorf("mode %d: logits = %v, want %v", tc.mode, got, tc.want)
+				break
+			}
+		}
+	}
+}
+
+func TestStitcher_CenterTies(t *testing.T) {
+	// Token 3 is 1 token from the cut edge of both chunks
+	first := chunk{seq: 0, start: 0, end: 5}
+	second := chunk{seq: 0, start: 2, end: 7}
+	firstLogits := []float32{1, 1, 1, 1, 1}
+	secondLogits := []float32{5, 5, 5, 5, 5}
+	want := []float32{1, 1, 1, 1, 5, 5, 5}
+
+	for _, reversed := range []bool{false, true} {
+		st := newStitcher(StitchCenter, 1, []int{7})
+		if reversed {
+			st.add(second, secondLogits)
+			st.add(first, firstLogits)
+		} else {
+			st.add(first, firstLogits)
+			st.add(second, secondLogits)
+		}
+		if got := st.result()[0]; !slices.Equal(got, want) {
+			t.Errorf("reversed %t: logits = %v, want %v", reversed, got, want)
+		}
+	}
+}
+
+// recordingBackend records the rows passed to a sattest.Backend.
+type recordingBackend struct {
+	*sattest.Backend
+	rows [][]int64
+}
+
+func (b *recordingBackend) InferBatchLabels(ctx context.Context, batch [][]int64, padID int64) ([][]float32, error) {
+	b.rows = append(b.rows, batch...)
+	return b.Backend.InferBatchLabels(ctx, batch, padID)
+}
+
+func TestSegment_SpecialTokens(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	text := strings.TrimSpace(strings.Repeat("One two three. Four five six! ", 5))
+
+	var want []string
+	for _, special := range []bool{true, false} {
+		backend := &recordingBackend{Backend: sattest.NewSentenceBackend(tok)}
+		backend.MaxLen = 16
+		// A boundary on </s> would leak into the text if it were not dropped
+		backend.Boundaries[int64(tok.EOSID())] = true
+
+		seg, err := NewWithBackend(backend, tok, WithSpecialTokens(special))
+		if err != nil {
+			t.Fatalf("NewWithBackend() failed: %v", err)
+		}
+		got, err := seg.Segment(context.Background(), text)
+		_ = seg.Close()
+		if err != nil {
+			t.Fatalf("Segment failed: %v", err)
+		}
+
+		for _, row := range backend.rows {
+			if len(row) > backend.MaxLen {
+				t.Errorf("row of %d tokens exceeds MaxLen %d", len(row), backend.MaxLen)
+			}
+			framed := row[0] == int64(tok.BOSID()) && row[len(row)-1] == int64(tok.EOSID())
+			if framed != special {
+				t.Errorf("special tokens %v: row %v framed = %v", special, row, framed)
+			}
+		}
+
+		if want == nil {
+			want = got
+			if len(want) != 10 {
+				t.Errorf("expected 10 sentences, got %d: %q", len(want), want)
+			}
+		} else if !slices.Equal(got, want) {
+			t.Errorf("special tokens %v: got %q, want %q", special, got, want)
+		}
+	}
+}
+
+func TestSegment_Stitching(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	text := strings.TrimSpace(strings.Repeat("One two three. Four five six! ", 10))
+
+	for _, mode := range []Stitching{StitchMean, StitchWeighted, StitchCenter} {
+		backend := sattest.NewSentenceBackend(tok)
+		backend.MaxLen = 16
+		seg, err := NewWithBackend(backend, tok, WithStitching(mode), WithChunkOverlap(6))
+		if err != nil {
+			t.Fatalf("NewWithBackend() failed: %v", err)
+		}
+		got, err := seg.Segment(context.Background(), text)
+		_ = seg.Close()
+		if err != nil {
+			t.Fatalf("Segment failed: %v", err)
+		}
+		if len(got) != 20 {
+			t.Errorf("mode %d: expected 20 sentences, got %d", mode, len(got))
+		}
+	}
+}
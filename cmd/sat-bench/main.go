@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 
 	sat "github.com/jamesainslie/go-sat"
@@ -25,6 +26,7 @@ func main() {
 		sweepMax      = flag.Float64("sweep-max", 0.20, "Sweep maximum threshold")
 		sweepStep     = flag.Float64("sweep-step", 0.01, "Sweep step size")
 		models        = flag.String("models", "", "Comma-separated model paths for comparison")
+		langThreshold = flag.String("lang-thresholds", "", "Per-language thresholds, e.g. ja=0.1,th=0.3")
 	)
 	flag.Parse()
 
@@ -47,11 +49,18 @@ func main() {
 	}
 	fmt.Printf("Loaded %d talks from %s\n\n", len(talks), *corpusDir)
 
+	langThresholds, err := parseLanguageThresholds(*langThreshold)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: -lang-thresholds: %v\n", err)
+		os.Exit(1)
+	}
+
 	cfg := bench.Config{
-		Threshold:       float32(*threshold),
-		Tolerance:       *tolerance,
-		PrecisionWeight: *wp,
-		RecallWeight:    *wr,
+		Threshold:          float32(*threshold),
+		LanguageThresholds: langThresholds,
+		Tolerance:          *tolerance,
+		PrecisionWeight:    *wp,
+		RecallWeight:       *wr,
 	}
 
 	ctx := context.Background()
@@ -78,6 +87,7 @@ func runSingle(ctx context.Context, modelPath, tokenizerPath string, talks []*be
 	defer func() { _ = seg.Close() }()
 
 	var totalTP, totalFP, totalFN int
+	byLanguage := make(map[string]*bench.Metrics)
 	for _, talk := range talks {
 		m, err := bench.EvaluateTalk(ctx, seg, talk, cfg)
 		if err != nil {
@@ -87,9 +97,30 @@ func runSingle(ctx context.Context, modelPath, tokenizerPath string, talks []*be
 		totalTP += m.TruePositives
 		totalFP += m.FalsePositives
 		totalFN += m.FalseNegatives
+
+		lang := byLanguage[talk.Language]
+		if lang == nil {
+			lang = &bench.Metrics{}
+			byLanguage[talk.Language] = lang
+		}
+		lang.TruePositives += m.TruePositives
+		lang.FalsePositives += m.FalsePositives
+		lang.FalseNegatives += m.FalseNegatives
 	}
 
 	printMetrics(totalTP, totalFP, totalFN, cfg)
+
+	if languages := bench.Languages(talks); len(languages) > 1 || (len(languages) == 1 && languages[0] != "") {
+		fmt.Printf("\nPer-Language Results\n")
+		fmt.Println(strings.Repeat("-", 60))
+		fmt.Printf("%-8s %-8s %-8s %-8s %-8s %-8s\n", "Lang", "Thresh", "Prec", "Rec", "F1", "Weighted")
+		for _, lang := range languages {
+			t := byLanguage[lang]
+			m := bench.Aggregate(t.TruePositives, t.FalsePositives, t.FalseNegatives, cfg)
+			fmt.Printf("%-8s %-8.3f %-8.2f %-8.2f %-8.2f %-8.2f\n",
+				languageLabel(lang), cfg.ThresholdFor(lang), m.Precision, m.Recall, m.F1, m.WeightedScore)
+		}
+	}
 }
 
 func runSweep(ctx context.Context, modelPath, tokenizerPath string, talks []*bench.Talk, cfg bench.Config, min, max, step float32) {
@@ -99,11 +130,12 @@ func runSweep(ctx context.Context, modelPath, tokenizerPath string, talks []*ben
 	fmt.Println(strings.Repeat("-", 50))
 	fmt.Printf("%-8s %-8s %-8s %-8s %-8s\n", "Thresh", "Prec", "Rec", "F1", "Weighted")
 
-	results, err := bench.Sweep(ctx, talks, modelPath, tokenizerPath, cfg, thresholds)
+	report, err := bench.SweepLanguages(ctx, talks, modelPath, tokenizerPath, cfg, thresholds)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error during sweep: %v\n", err)
 		os.Exit(1)
 	}
+	results := report.Overall
 
 	// Print sorted by threshold for readability
 	for _, t := range thresholds {
@@ -121,6 +153,21 @@ func runSweep(ctx context.Context, modelPath, tokenizerPath string, talks []*ben
 		best := results[0]
 		fmt.Printf("Optimal: %.3f (Weighted: %.2f)\n", best.Threshold, best.Metrics.WeightedScore)
 	}
+
+	languages := bench.Languages(talks)
+	if len(languages) < 2 {
+		return
+	}
+	fmt.Printf("\nPer-Language Optimal Thresholds\n")
+	fmt.Println(strings.Repeat("-", 50))
+	fmt.Printf("%-8s %-8s %-8s %-8s %-8s %-8s\n", "Lang", "Thresh", "Prec", "Rec", "F1", "Weighted")
+	for _, lang := range languages {
+		if results := report.ByLanguage[lang]; len(results) > 0 {
+			best := results[0]
+			fmt.Printf("%-8s %-8.3f %-8.2f %-8.2f %-8.2f %-8.2f\n",
+				languageLabel(lang), best.Threshold, best.Metrics.Precision, best.Metrics.Recall, best.Metrics.F1, best.Metrics.WeightedScore)
+		}
+	}
 }
 
 func runModelComparison(ctx context.Context, modelPaths []string, tokenizerPath string, talks []*bench.Talk, cfg bench.Config, sweep bool, min, max, step float32) {
@@ -159,7 +206,7 @@ func runModelComparison(ctx context.Context, modelPaths []string, tokenizerPath
 			_ = seg.Close()
 
 			bestThreshold = cfg.Threshold
-			bestMetrics = computeMetrics(totalTP, totalFP, totalFN, cfg)
+			bestMetrics = bench.Aggregate(totalTP, totalFP, totalFN, cfg)
 		}
 
 		fmt.Printf("%-30s %-8.3f %-8.2f %-8.2f\n", modelPath, bestThreshold, bestMetrics.F1, bestMetrics.WeightedScore)
@@ -167,29 +214,38 @@ func runModelComparison(ctx context.Context, modelPaths []string, tokenizerPath
 }
 
 func printMetrics(tp, fp, fn int, cfg bench.Config) {
-	m := computeMetrics(tp, fp, fn, cfg)
+	m := bench.Aggregate(tp, fp, fn, cfg)
 	fmt.Printf("Precision: %.2f  Recall: %.2f  F1: %.2f  Weighted: %.2f\n",
 		m.Precision, m.Recall, m.F1, m.WeightedScore)
 	fmt.Printf("(TP: %d, FP: %d, FN: %d)\n", tp, fp, fn)
 }
 
-func computeMetrics(tp, fp, fn int, cfg bench.Config) bench.Metrics {
-	m := bench.Metrics{
-		TruePositives:  tp,
-		FalsePositives: fp,
-		FalseNegatives: fn,
-	}
-	if tp+fp > 0 {
-		m.Precision = float64(tp) / float64(tp+fp)
-	}
-	if tp+fn > 0 {
-		m.Recall = float64(tp) / float64(tp+fn)
-	}
-	if m.Precision+m.Recall > 0 {
-		m.F1 = 2 * m.Precision * m.Recall / (m.Precision + m.Recall)
+// parseLanguageThresholds parses a comma-separated list of lang=threshold
+// pairs.
+func parseLanguageThresholds(spec string) (map[string]float32, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	thresholds := make(map[string]float32)
+	for _, pair := range strings.Split(spec, ",") {
+		lang, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(lang) == "" {
+			return nil, fmt.Errorf("invalid entry %q, want lang=threshold", pair)
+		}
+		t, err := strconv.ParseFloat(strings.TrimSpace(value), 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid threshold for %s: %w", lang, err)
+		}
+		thresholds[strings.TrimSpace(lang)] = float32(t)
 	}
-	if cfg.PrecisionWeight+cfg.RecallWeight > 0 {
-		m.WeightedScore = (cfg.PrecisionWeight*m.Precision + cfg.RecallWeight*m.Recall) / (cfg.PrecisionWeight + cfg.RecallWeight)
+	return thresholds, nil
+}
+
+// languageLabel names lang in reports, showing talks without a language as
+// "-".
+func languageLabel(lang string) string {
+	if lang == "" {
+		return "-"
 	}
-	return m
+	return lang
 }
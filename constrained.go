@@ -0,0 +1,223 @@
+package sat
+
+import (
+	"math"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/jamesainslie/go-sat/tokenizer"
+)
+
+// LengthUnit selects how sentence lengths are measured for WithMinLength and
+// WithMaxLength.
+type LengthUnit int
+
+const (
+	// LengthChars measures sentences in characters (Unicode code points),
+	// including surrounding whitespace.
+	LengthChars LengthUnit = iota
+
+	// LengthTokens measures sentences in tokenizer tokens.
+	LengthTokens
+)
+
+// lengthConstraint bounds the length of every sentence. A zero bound is
+// unset.
+type lengthConstraint struct {
+	min  int
+	max  int
+	unit LengthUnit
+}
+
+// enabled reports whether any bound is set.
+func (lc lengthConstraint) enabled() bool {
+	return lc.min > 0 || lc.max > 0
+}
+
+// maxLogit bounds the split scores so that probabilities of exactly 0 or 1
+// do not produce infinities.
+const maxLogit = 30
+
+// sentenceSplits returns the token indices after which text is split into
+// sentences: every token above threshold, or, when a length constraint is
+// set, the constrained optimum chosen by constrainedSplits.
+func (c callConfig) sentenceSplits(text string, tokens []tokenizer.TokenInfo, probs []float32, threshold float32) []int {
+	if !c.lengths.enabled() {
+		return thresholdSplits(probs, threshold)
+	}
+	return c.lengthSplits(text, tokens, probs, threshold)
+}
+
+// lengthSplits returns the constrained splits for the length limits of c.
+// The search only sees the splits adjustSplits leaves in place, so moving
+// splits past closing punctuation or off whitespace cannot break the limits.
+// Each split is returned at its most probable source, which adjustSplits
+// moves back onto the chosen point along with its probability.
+func (c callConfig) lengthSplits(text string, tokens []tokenizer.TokenInfo, probs []float32, threshold float32) []int {
+	folded, source := c.adjustedCandidates(text, tokens, probs)
+	if source == nil {
+		return constrainedSplits(text, tokens, probs, threshold, c.lengths, nil)
+	}
+
+	allowed := make([]bool, len(source))
+	for j, i := range source {
+		allowed[j] = i >= 0
+	}
+	splits := constrainedSplits(text, tokens, folded, threshold, c.lengths, allowed)
+	for k, j := range splits {
+		splits[k] = source[j]
+	}
+	return splits
+}
+
+// constrainedSplits picks split points by dynamic programming so that every
+// sentence is between lc.min and lc.max long, following the Viterbi decoding
+// of wtpsplit's constrained segmentation.
+//
+// Splitting after token i scores logit(p_i) - logit(threshold), so tokens
+// above threshold add to the total and tokens below it subtract. The chosen
+// splits maximize the total score among segmentations that fit the limits;
+// without limits that is exactly the set of tokens above threshold. When no
+// segmentation fits, as when the text is shorter than lc.min or a single
+// token is longer than lc.max, the limits are exceeded by as little as the
+// search allows: a sentence longer than lc.max is only ever a single token.
+//
+// If allowed is not nil, a split after token i is only considered where
+// allowed[i] is set; a sentence longer than lc.max then runs between two
+// adjacent allowed splits.
+func constrainedSplits(text string, tokens []tokenizer.TokenInfo, probs []float32, threshold float32, lc lengthConstraint, allowed []bool) []int {
+	n := min(len(tokens), len(probs))
+	if n == 0 {
+		return nil
+	}
+
+	// pos[p] is the length of the text up to split point p, which lies
+	// after token p-1, or after the punctuation inside it (see splitEnd);
+	// point n is the end of the text.
+	pos := make([]int, n+1)
+	prevEnd := 0
+	for p := 1; p <= n; p++ {
+		if lc.unit == LengthTokens {
+			pos[p] = p
+			continue
+		}
+		end := len(text)
+		if p < n {
+			end = min(max(splitEnd(text, tokens[p-1]), prevEnd), len(text))
+		}
+		pos[p] = pos[p-1] + utf8.RuneCountInString(text[prevEnd:end])
+		prevEnd = end
+	}
+
+	bias := logit(threshold)
+	score := func(p int) float64 {
+		if p == n {
+			return 0
+		}
+		return logit(probs[p-1]) - bias
+	}
+	violation := func(length int) int {
+		v := 0
+		if lc.min > 0 && length < lc.min {
+			v += lc.min - length
+		}
+		if lc.max > 0 && length > lc.max {
+			v += length - lc.max
+		}
+		return v
+	}
+
+	// best[p] is the best segmentation of the text up to split point p
+	type state struct {
+		violation int
+		score     float64
+		prev      int
+	}
+	better := func(a, b state) bool {
+		if a.violation != b.violation {
+			return a.violation < b.violation
+		}
+		return a.score > b.score
+	}
+	best := make([]state, n+1)
+	// canSplit reports whether split point p, after token p-1, is a
+	// candidate; the start and end of the text always are
+	canSplit := func(p int) bool {
+		return p == 0 || p == n || allowed == nil || allowed[p-1]
+	}
+
+	// Without a maximum, prefix is the best of the split points before
+	// prefixEnd, which are all at least lc.min before the current one
+	prefix, prefixEnd := -1, 0
+
+	for p := 1; p <= n; p++ {
+		if !canSplit(p) {
+			continue
+		}
+		cur := state{violation: math.MaxInt}
+		consider := func(j int) {
+			cand := best[j]
+			cand.violation += violation(pos[p] - pos[j])
+			cand.score += score(p)
+			cand.prev = j
+			if cur.violation == math.MaxInt || better(cand, cur) {
+				cur = cand
+			}
+		}
+
+		// Scan back over previous split points whose segments are short
+		// enough to consider; the nearest candidate always is
+		adjacent := true
+		for j := p - 1; j >= 0; j-- {
+			if !canSplit(j) {
+				continue
+			}
+			length := pos[p] - pos[j]
+			if !adjacent && lc.max > 0 && length > lc.max {
+				break
+			}
+			if !adjacent && lc.max == 0 && length >= lc.min {
+				break
+			}
+			consider(j)
+			adjacent = false
+		}
+
+		if lc.max == 0 {
+			for prefixEnd < p && pos[p]-pos[prefixEnd] >= lc.min {
+				if !canSplit(prefixEnd) {
+					prefixEnd++
+					continue
+				}
+				if prefix < 0 || better(best[prefixEnd], best[prefix]) {
+					prefix = prefixEnd
+				}
+				prefixEnd++
+			}
+			if prefix >= 0 {
+				consider(prefix)
+			}
+		}
+
+		best[p] = cur
+	}
+
+	var splits []int
+	for p := best[n].prev; p > 0; p = best[p].prev {
+		splits = append(splits, p-1)
+	}
+	slices.Reverse(splits)
+	return splits
+}
+
+// logit returns the inverse sigmoid of p, bounded by ±maxLogit.
+func logit(p float32) float64 {
+	x := float64(p)
+	if x <= 0 {
+		return -maxLogit
+	}
+	if x >= 1 {
+		return maxLogit
+	}
+	return max(-maxLogit, min(maxLogit, math.Log(x/(1-x))))
+}
@@ -0,0 +1,328 @@
+package sat
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/jamesainslie/go-sat/sattest"
+	"github.com/jamesainslie/go-sat/tokenizer"
+)
+
+func TestConstrainedSplits_Unconstrained(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	text := "One. Two three four. Five"
+	tokens := tok.Encode(text)
+	probs := make([]float32, len(tokens))
+	for i, tk := range tokens {
+		if tk.Text == "." {
+			probs[i] = 0.9
+		}
+	}
+
+	// Limits every segmentation satisfies leave the threshold splits
+	lc := lengthConstraint{min: 1, max: 1000, unit: LengthChars}
+	got := constrainedSplits(text, tokens, probs, 0.5, lc, nil)
+	if want := thresholdSplits(probs, 0.5); !slices.Equal(got, want) {
+		t.Errorf("constrainedSplits() = %v, want %v", got, want)
+	}
+}
+
+func TestConstrainedSplits_Chars(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	text := "One. Two three four five six seven eight. Nine. Ten"
+	tokens := tok.Encode(text)
+	probs := make([]float32, len(tokens))
+	for i, tk := range tokens {
+		if tk.Text == "." {
+			probs[i] = 0.9
+		}
+	}
+
+	tests := []struct {
+		name string
+		lc   lengthConstraint
+	}{
+		{"max", lengthConstraint{max: 20}},
+		{"min", lengthConstraint{min: 10}},
+		{"both", lengthConstraint{min: 8, max: 16}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			splits := constrainedSplits(text, tokens, probs, 0.5, tc.lc, nil)
+			sentences := buildSentences(text, tokens, probs, splits)
+			for _, sent := range sentences {
+				n := utf8.RuneCountInString(sent.Text)
+				if (tc.lc.min > 0 && n < tc.lc.min) || (tc.lc.max > 0 && n > tc.lc.max) {
+					t.Errorf("sentence %q has %d chars, want [%d, %d]", sent.Text, n, tc.lc.min, tc.lc.max)
+				}
+			}
+			if got := sentences[len(sentences)-1].End; got != len(text) {
+				t.Errorf("sentences end at %d, want %d", got, len(text))
+			}
+		})
+	}
+
+	// A sentence boundary that fits the limits is kept
+	splits := constrainedSplits(text, tokens, probs, 0.5, lengthConstraint{min: 8}, nil)
+	sentences := buildSentences(text, tokens, probs, splits)
+	if sentences[0].Text != "One. Two three four five six seven eight." {
+		t.Errorf("first sentence = %q", sentences[0].Text)
+	}
+}
+
+func TestConstrainedSplits_Infeasible(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	text := "Hi. Yo."
+	tokens := tok.Encode(text)
+	probs := make([]float32, len(tokens))
+	for i, tk := range tokens {
+		if tk.Text == "." {
+			probs[i] = 0.9
+		}
+	}
+
+	// Shorter than the minimum: a single sentence
+	if got := constrainedSplits(text, tokens, probs, 0.5, lengthConstraint{min: 100}, nil); len(got) != 0 {
+		t.Errorf("splits = %v, want none", got)
+	}
+
+	// Every token is longer than the maximum: every token on its own
+	got := constrainedSplits(text, tokens, probs, 0.5, lengthConstraint{max: 1, unit: LengthChars}, nil)
+	if len(got) != len(tokens)-1 {
+		t.Errorf("splits = %v, want one after every token but the last", got)
+	}
+}
+
+// bruteForceSplits returns the best score of any segmentation of n tokens
+// into parts of [lo, hi] tokens, or -Inf if there is none.
+func bruteForceSplits(probs []float32, threshold float32, lo, hi int) float64 {
+	n := len(probs)
+	best := math.Inf(-1)
+	for mask := 0; mask < 1<<(n-1); mask++ {
+		score, start, ok := 0.0, 0, true
+		for p := 1; p <= n && ok; p++ {
+			if p < n && mask&(1<<(p-1)) == 0 {
+				continue
+			}
+			if length := p - start; length < lo || (hi > 0 && length > hi) {
+				ok = false
+			}
+			if p < n {
+				score += logit(probs[p-1]) - logit(threshold)
+			}
+			start = p
+		}
+		if ok {
+			best = max(best, score)
+		}
+	}
+	return best
+}
+
+func TestConstrainedSplits_Optimal(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	text := "abcdefghijkl"
+	tokens := tok.Encode(text)
+	rng := rand.New(rand.NewPCG(1, 2))
+
+	for iter := range 200 {
+		n := 1 + rng.IntN(len(tokens))
+		probs := make([]float32, n)
+		for i := range probs {
+			probs[i] = rng.Float32()
+		}
+		lc := lengthConstraint{min: rng.IntN(4), max: rng.IntN(6), unit: LengthTokens}
+		if lc.max > 0 {
+			lc.max = max(lc.max, lc.min)
+		}
+		if !lc.enabled() {
+			continue
+		}
+
+		want := bruteForceSplits(probs, 0.3, lc.min, lc.max)
+		if math.IsInf(want, -1) {
+			continue
+		}
+
+		splits := constrainedSplits(text, tokens[:n], probs, 0.3, lc, nil)
+		score, start := 0.0, 0
+		for _, i := range append(splits, n-1) {
+			if length := i + 1 - start; length < lc.min || (lc.max > 0 && length > lc.max) {
+				t.Fatalf("iter %d %+v: splits %v give a segment of %d tokens", iter, lc, splits, length)
+			}
+			if i < n-1 {
+				score += logit(probs[i]) - logit(0.3)
+			}
+			start = i + 1
+		}
+		if math.Abs(score-want) > 1e-9 {
+			t.Fatalf("iter %d %+v: score %v, want %v", iter, lc, score, want)
+		}
+	}
+}
+
+func TestSegment_LengthLimits(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	text := "This is a sentence without any boundary at all, going on and on and on"
+
+	seg, err := NewWithBackend(sattest.NewSentenceBackend(tok), tok, WithMaxLength(8), WithLengthUnit(LengthTokens))
+	if err != nil {
+		t.Fatalf("NewWithBackend() failed: %v", err)
+	}
+	defer func() { _ = seg.Close() }()
+
+	sentences, err := seg.SegmentDetailed(context.Background(), text)
+	if err != nil {
+		t.Fatalf("SegmentDetailed failed: %v", err)
+	}
+	if len(sentences) < 2 {
+		t.Fatalf("expected the text to be split, got %d sentence(s)", len(sentences))
+	}
+	var joined strings.Builder
+	for _, sent := range sentences {
+		if n := sent.TokenEnd - sent.TokenStart; n > 8 {
+			t.Errorf("sentence %q has %d tokens, want at most 8", sent.Text, n)
+		}
+		joined.WriteString(sent.Text)
+	}
+	if joined.String() != text {
+		t.Errorf("sentences do not cover the input: %q", joined.String())
+	}
+
+	batch, err := seg.SegmentBatch(context.Background(), []string{text})
+	if err != nil {
+		t.Fatalf("SegmentBatch failed: %v", err)
+	}
+	if len(batch[0]) != len(sentences) {
+		t.Errorf("SegmentBatch returned %d sentences, SegmentDetailed %d", len(batch[0]), len(sentences))
+	}
+}
+
+func TestNewWithBackend_InvalidLength(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+
+	_, err := NewWithBackend(sattest.NewSentenceBackend(tok), tok, WithMinLength(10), WithMaxLength(5))
+	if !errors.Is(err, ErrInvalidLength) {
+		t.Errorf("expected ErrInvalidLength, got: %v", err)
+	}
+}
+
+func TestSegment_LengthLimitsStraddlingTokens(t *testing.T) {
+	// Lengths count to the punctuation, not to the end of "。我们": the
+	// first sentence is 3 characters, too short to stand alone
+	seg := newCJKSegmenter(t, WithMinLength(4), WithLengthUnit(LengthChars))
+
+	got, err := seg.Segment(context.Background(), "你好。我们很好。今天很好。")
+	if err != nil {
+		t.Fatalf("Segment() failed: %v", err)
+	}
+	want := []string{"你好。我们很好。", "今天很好。"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Segment() = %q, want %q", got, want)
+	}
+}
+
+func TestSentences_LengthLimitsAdjustedSplits(t *testing.T) {
+	quoted := `He said "Hi." Ok.` + "\n"
+	quotedTokens := []tokenizer.TokenInfo{
+		{Text: "▁He", Start: 0, End: 2},
+		{Text: "▁said", Start: 2, End: 7},
+		{Text: "▁\"Hi", Start: 7, End: 11},
+		{Text: ".", Start: 11, End: 12},
+		{Text: "\"", Start: 12, End: 13},
+		{Text: "▁Ok", Start: 13, End: 16},
+		{Text: ".", Start: 16, End: 17},
+		{Text: "\n", Start: 17, End: 18},
+	}
+	spaced := "Ab.\nCdef."
+	spacedTokens := []tokenizer.TokenInfo{
+		{Text: "▁Ab", Start: 0, End: 2},
+		{Text: ".", Start: 2, End: 3},
+		{Text: "\n", Start: 3, End: 4},
+		{Text: "Cdef", Start: 4, End: 8},
+		{Text: ".", Start: 8, End: 9},
+	}
+
+	tests := []struct {
+		name   string
+		text   string
+		tokens []tokenizer.TokenInfo
+		probs  []float32
+		cc     callConfig
+		want   []string
+	}{
+		{
+			// `He said "Hi."` is 13 characters once the quote is attached
+			name:   "attach closing",
+			text:   quoted,
+			tokens: quotedTokens,
+			probs:  []float32{0, 0.2, 0, 0.9, 0.1, 0, 0.2, 0.8},
+			cc:     callConfig{attachClosing: true, lengths: lengthConstraint{max: 12}},
+			want:   []string{"He said", ` "Hi."`, " Ok.\n"},
+		},
+		{
+			// Moving the split before the newline makes "\nCdef." 6 long
+			name:   "skip whitespace tokens",
+			text:   spaced,
+			tokens: spacedTokens,
+			probs:  []float32{0, 0.1, 0.9, 0.2, 0.9},
+			cc:     callConfig{skipWhitespaceTokens: true, lengths: lengthConstraint{max: 5}},
+			want:   []string{"Ab.", "\nCdef", "."},
+		},
+		{
+			name:   "both within limits",
+			text:   quoted,
+			tokens: quotedTokens,
+			probs:  []float32{0, 0, 0, 0.9, 0.1, 0, 0.2, 0.8},
+			cc:     callConfig{attachClosing: true, skipWhitespaceTokens: true, lengths: lengthConstraint{max: 13}},
+			want:   []string{`He said "Hi."`, " Ok.", "\n"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.cc.threshold = 0.5
+			splits := tc.cc.documentSplits(tc.text, tc.tokens, tc.probs)
+			var texts []string
+			for _, sent := range tc.cc.sentences(tc.text, tc.tokens, tc.probs, splits) {
+				texts = append(texts, sent.Text)
+				if n := utf8.RuneCountInString(sent.Text); n > tc.cc.lengths.max {
+					t.Errorf("sentence %q has %d characters, want at most %d", sent.Text, n, tc.cc.lengths.max)
+				}
+			}
+			if !slices.Equal(texts, tc.want) {
+				t.Errorf("sentences = %q, want %q", texts, tc.want)
+			}
+		})
+	}
+}
+
+func TestSegment_LengthLimitsAttachClosing(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	seg, err := NewWithBackend(sattest.NewSentenceBackend(tok), tok)
+	if err != nil {
+		t.Fatalf("NewWithBackend() failed: %v", err)
+	}
+	defer func() { _ = seg.Close() }()
+
+	text := `Abcdefghi." Xy.`
+	got, err := seg.Segment(context.Background(), text, CallMaxLength(10), CallAttachClosing(true), CallSkipWhitespaceTokens(true))
+	if err != nil {
+		t.Fatalf("Segment() failed: %v", err)
+	}
+	if strings.Join(got, "") != text {
+		t.Errorf("sentences do not cover the input: %q", got)
+	}
+	for _, sent := range got {
+		if n := utf8.RuneCountInString(sent); n > 10 {
+			t.Errorf("sentence %q has %d characters, want at most 10", sent, n)
+		}
+	}
+}
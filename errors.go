@@ -12,4 +12,13 @@ var (
 
 	// ErrTokenizerFailed indicates tokenizer initialization failed.
 	ErrTokenizerFailed = errors.New("sat: tokenizer initialization failed")
+
+	// ErrInvalidLabel indicates a label index the model does not predict.
+	ErrInvalidLabel = errors.New("sat: label out of range")
+
+	// ErrInvalidAdapter indicates an adapter that cannot be read or applied.
+	ErrInvalidAdapter = errors.New("sat: invalid adapter")
+
+	// ErrInvalidLength indicates a minimum sentence length above the maximum.
+	ErrInvalidLength = errors.New("sat: invalid sentence length limits")
 )
@@ -0,0 +1,197 @@
+package inference
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AdapterConfigFile is the name of the JSON file, stored next to adapter
+// weights by PEFT and AdapterHub, from which LoadAdapter reads the LoRA
+// scaling.
+const AdapterConfigFile = "adapter_config.json"
+
+// Adapter holds LoRA weights that adapt a SaT model to a style or domain,
+// such as the lyrics, verse and legal adapters published with wtpsplit. It
+// is applied by the pure Go backend when loading a model (see
+// NativeConfig.Adapter), merging each low-rank update into the weight it
+// targets.
+type Adapter struct {
+	loras    map[string]loraPair   // by target weight name, e.g. "...query.weight"
+	replaced map[string]tensorData // weights replaced outright, e.g. a new classifier
+	alpha    float32               // scaling numerator, or 0 for scaling 1
+}
+
+// loraPair is the low-rank update B·A of one weight matrix.
+type loraPair struct {
+	a, b tensorData // A is [rank, in], B is [out, rank]
+}
+
+// LoadAdapter loads LoRA adapter weights from a safetensors file. If the
+// file's directory also holds an adapter_config.json, its lora_alpha (PEFT)
+// or alpha (AdapterHub) sets the scaling alpha/rank; otherwise the scaling
+// is 1.
+func LoadAdapter(path string) (*Adapter, error) {
+	weights, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("adapter file: %w", err)
+	}
+	config, err := os.ReadFile(filepath.Join(filepath.Dir(path), AdapterConfigFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("adapter config: %w", err)
+	}
+
+	return LoadAdapterFromBytes(weights, config)
+}
+
+// LoadAdapterFromBytes loads LoRA adapter weights from the contents of a
+// safetensors file and, if config is not nil, of its adapter_config.json.
+// The returned adapter references weights, which must not be modified.
+//
+// Tensor names follow PEFT ("base_model.model.<module>.lora_A.weight") or
+// AdapterHub ("<module>.loras.<name>.lora_A"). Other tensors, such as a
+// domain-specific classifier, replace the model weight of the same name.
+func LoadAdapterFromBytes(weights, config []byte) (*Adapter, error) {
+	tensors, err := loadSafetensors(weights)
+	if err != nil {
+		return nil, err
+	}
+
+	ad := &Adapter{
+		loras:    make(map[string]loraPair),
+		replaced: make(map[string]tensorData),
+	}
+	for name, t := range tensors {
+		name = strings.TrimPrefix(name, "base_model.model.")
+		module, part, ok := loraTarget(name)
+		if !ok {
+			ad.replaced[name] = t
+			continue
+		}
+		pair := ad.loras[module+".weight"]
+		if part == "A" {
+			pair.a = t
+		} else {
+			pair.b = t
+		}
+		ad.loras[module+".weight"] = pair
+	}
+	for target, pair := range ad.loras {
+		if pair.a.raw == nil || pair.b.raw == nil {
+			return nil, fmt.Errorf("%w: adapter for %s lacks lora_A or lora_B", ErrInvalidWeights, target)
+		}
+		if len(pair.a.shape) != 2 || len(pair.b.shape) != 2 || pair.a.shape[0] != pair.b.shape[1] {
+			return nil, fmt.Errorf("%w: adapter for %s has shapes %v and %v", ErrInvalidWeights, target, pair.a.shape, pair.b.shape)
+		}
+	}
+
+	if config != nil {
+		var cfg struct {
+			LoraAlpha *float32 `json:"lora_alpha"`
+			Alpha     *float32 `json:"alpha"`
+		}
+		if err := json.Unmarshal(config, &cfg); err != nil {
+			return nil, fmt.Errorf("%w: parsing adapter config: %w", ErrInvalidWeights, err)
+		}
+		switch {
+		case cfg.LoraAlpha != nil:
+			ad.alpha = *cfg.LoraAlpha
+		case cfg.Alpha != nil:
+			ad.alpha = *cfg.Alpha
+		}
+	}
+
+	return ad, nil
+}
+
+// loraTarget splits a LoRA tensor name into the module it adapts and the
+// factor it holds, "A" or "B". ok is false for other tensors.
+func loraTarget(name string) (module, part string, ok bool) {
+	name = strings.TrimSuffix(name, ".weight")
+	switch {
+	case strings.HasSuffix(name, ".lora_A"):
+		module, part = strings.TrimSuffix(name, ".lora_A"), "A"
+	case strings.HasSuffix(name, ".lora_B"):
+		module, part = strings.TrimSuffix(name, ".lora_B"), "B"
+	default:
+		return "", "", false
+	}
+
+	// AdapterHub nests the factors under the adapter's name
+	if i := strings.LastIndex(module, ".loras."); i >= 0 {
+		module = module[:i]
+	}
+	return module, part, true
+}
+
+// apply returns tensors with the adapter merged in. The input map and its
+// tensors are not modified. Weight names in the adapter may include or omit
+// the "roberta." prefix of token classification checkpoints.
+func (ad *Adapter) apply(tensors map[string]tensorData) (map[string]tensorData, error) {
+	merged := make(map[string]tensorData, len(tensors))
+	for name, t := range tensors {
+		merged[name] = t
+	}
+
+	for name, t := range ad.replaced {
+		target, ok := adapterTarget(tensors, name)
+		if !ok {
+			return nil, fmt.Errorf("%w: adapter weight %s matches no model weight", ErrInvalidWeights, name)
+		}
+		merged[target] = t
+	}
+
+	for name, pair := range ad.loras {
+		target, ok := adapterTarget(tensors, name)
+		if !ok {
+			return nil, fmt.Errorf("%w: adapter targets missing weight %s", ErrInvalidWeights, name)
+		}
+		weight := merged[target]
+		rank, in, out := pair.a.shape[0], pair.a.shape[1], pair.b.shape[0]
+		if len(weight.shape) != 2 || weight.shape[0] != out || weight.shape[1] != in {
+			return nil, fmt.Errorf("%w: adapter for %s is [%d %d], weight is %v", ErrInvalidWeights, target, out, in, weight.shape)
+		}
+
+		scale := float32(1)
+		if ad.alpha != 0 {
+			scale = ad.alpha / float32(rank)
+		}
+		w := weight.float32s()
+		a, b := pair.a.float32s(), pair.b.float32s()
+		for o := range out {
+			row := w[o*in : (o+1)*in]
+			for r := range rank {
+				axpy(scale*b[o*rank+r], a[r*in:(r+1)*in], row)
+			}
+		}
+		merged[target] = float32Tensor(weight.shape, w)
+	}
+
+	return merged, nil
+}
+
+// adapterTarget returns the name under which tensors holds the weight an
+// adapter calls name.
+func adapterTarget(tensors map[string]tensorData, name string) (string, bool) {
+	candidates := []string{name, "roberta." + name, strings.TrimPrefix(name, "roberta.")}
+	for _, c := range candidates {
+		if _, ok := tensors[c]; ok {
+			return c, true
+		}
+	}
+	return "", false
+}
+
+// float32Tensor encodes values as a float32 tensor of the given shape.
+func float32Tensor(shape []int, values []float32) tensorData {
+	raw := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(raw[i*4:], math.Float32bits(v))
+	}
+	return tensorData{shape: shape, dtype: dtypeF32, raw: raw}
+}
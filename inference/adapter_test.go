@@ -0,0 +1,125 @@
+package inference
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAdapter_MatchesMergedWeights(t *testing.T) {
+	base := tinyModelWeights(1)
+	const target = "roberta.encoder.layer.0.attention.self.query.weight"
+
+	// Rank-1 update of the query weight, scaled by alpha/rank = 2
+	loraA := testTensor{shape: []int{1, 8}, data: []float32{1, 0, -1, 0, 0.5, 0, 0, 2}}
+	loraB := testTensor{shape: []int{8, 1}, data: []float32{0.1, -0.2, 0, 0.3, 0, 0, 0.4, -0.1}}
+	classifier := testTensor{shape: []int{2, 8}, data: make([]float32, 16)}
+	classifier.data[3] = 1
+
+	merged := make(map[string]testTensor, len(base))
+	for name, w := range base {
+		merged[name] = w
+	}
+	q := append([]float32(nil), base[target].data...)
+	for o := range 8 {
+		for i := range 8 {
+			q[o*8+i] += 2 * loraB.data[o] * loraA.data[i]
+		}
+	}
+	merged[target] = testTensor{shape: []int{8, 8}, data: q}
+	merged["classifier.weight"] = classifier
+
+	formats := map[string]map[string]testTensor{
+		"peft": {
+			"base_model.model.roberta.encoder.layer.0.attention.self.query.lora_A.weight": loraA,
+			"base_model.model.roberta.encoder.layer.0.attention.self.query.lora_B.weight": loraB,
+			"base_model.model.classifier.weight":                                          classifier,
+		},
+		"adapterhub": {
+			"roberta.encoder.layer.0.attention.self.query.loras.legal.lora_A": loraA,
+			"roberta.encoder.layer.0.attention.self.query.loras.legal.lora_B": loraB,
+			"classifier.weight": classifier,
+		},
+	}
+	configs := map[string]string{
+		"peft":       `{"r": 1, "lora_alpha": 2}`,
+		"adapterhub": `{"r": 1, "alpha": 2}`,
+	}
+
+	cfg := NativeConfig{NumHeads: 2}
+	want, err := LoadNativeModelFromBytes(encodeSafetensors(t, merged, false), cfg)
+	if err != nil {
+		t.Fatalf("loading merged model: %v", err)
+	}
+	ctx := context.Background()
+	batch := [][]int64{{0, 5, 9, 13, 2}}
+	wantLogits, err := want.InferBatch(ctx, batch, 1)
+	if err != nil {
+		t.Fatalf("InferBatch failed: %v", err)
+	}
+
+	for name, weights := range formats {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "adapter_model.safetensors")
+			if err := os.WriteFile(path, encodeSafetensors(t, weights, false), 0o600); err != nil {
+				t.Fatalf("write adapter: %v", err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, AdapterConfigFile), []byte(configs[name]), 0o600); err != nil {
+				t.Fatalf("write adapter config: %v", err)
+			}
+			adapter, err := LoadAdapter(path)
+			if err != nil {
+				t.Fatalf("LoadAdapter failed: %v", err)
+			}
+
+			cfg := cfg
+			cfg.Adapter = adapter
+			model, err := LoadNativeModelFromBytes(encodeSafetensors(t, base, false), cfg)
+			if err != nil {
+				t.Fatalf("loading adapted model: %v", err)
+			}
+			got, err := model.InferBatch(ctx, batch, 1)
+			if err != nil {
+				t.Fatalf("InferBatch failed: %v", err)
+			}
+			for j := range wantLogits[0] {
+				if diff := math.Abs(float64(got[0][j] - wantLogits[0][j])); diff > 1e-5 {
+					t.Errorf("token %d: got %v, want %v", j, got[0][j], wantLogits[0][j])
+				}
+			}
+		})
+	}
+}
+
+func TestAdapter_Invalid(t *testing.T) {
+	base := encodeSafetensors(t, tinyModelWeights(1), false)
+	tests := map[string]map[string]testTensor{
+		"missing factor": {
+			"roberta.encoder.layer.0.attention.self.query.lora_A.weight": {shape: []int{1, 8}, data: make([]float32, 8)},
+		},
+		"unknown target": {
+			"roberta.encoder.layer.7.attention.self.query.lora_A.weight": {shape: []int{1, 8}, data: make([]float32, 8)},
+			"roberta.encoder.layer.7.attention.self.query.lora_B.weight": {shape: []int{8, 1}, data: make([]float32, 8)},
+		},
+		"wrong shape": {
+			"roberta.encoder.layer.0.attention.self.query.lora_A.weight": {shape: []int{1, 4}, data: make([]float32, 4)},
+			"roberta.encoder.layer.0.attention.self.query.lora_B.weight": {shape: []int{8, 1}, data: make([]float32, 8)},
+		},
+	}
+
+	for name, weights := range tests {
+		t.Run(name, func(t *testing.T) {
+			adapter, err := LoadAdapterFromBytes(encodeSafetensors(t, weights, false), nil)
+			if err == nil {
+				_, err = LoadNativeModelFromBytes(base, NativeConfig{NumHeads: 2, Adapter: adapter})
+			}
+			if !errors.Is(err, ErrInvalidWeights) {
+				t.Errorf("error = %v, want ErrInvalidWeights", err)
+			}
+		})
+	}
+}
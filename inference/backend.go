@@ -0,0 +1,65 @@
+package inference
+
+import "context"
+
+// DefaultMaxSequenceLength is the sequence length limit of the SaT models.
+// Their position embeddings cover 514 positions, two of which are reserved
+// for padding.
+const DefaultMaxSequenceLength = 512
+
+// Backend runs a SaT model on batches of token IDs.
+//
+// Implementations need not be safe for concurrent use; a Pool hands each
+// backend to one caller at a time.
+type Backend interface {
+	// InferBatch returns per-token logits for each sequence in batch.
+	// Sequences may have different lengths; padID is the token ID used when
+	// an implementation needs to pad them to a common length.
+	InferBatch(ctx context.Context, batch [][]int64, padID int64) ([][]float32, error)
+
+	// MaxSequenceLength returns the longest sequence the model accepts.
+	MaxSequenceLength() int
+
+	// Close releases the backend's resources.
+	Close() error
+}
+
+// MultiLabelBackend is a Backend whose model predicts several labels per
+// token, such as WtP and SaT checkpoints with newline and auxiliary
+// punctuation heads. InferBatch returns only the first label.
+type MultiLabelBackend interface {
+	Backend
+
+	// NumLabels returns the number of labels predicted per token.
+	NumLabels() int
+
+	// InferBatchLabels is like InferBatch but returns the logits of every
+	// label. Row i holds len(batch[i])*NumLabels() values in row-major order,
+	// so label k of token t is at index t*NumLabels()+k.
+	InferBatchLabels(ctx context.Context, batch [][]int64, padID int64) ([][]float32, error)
+}
+
+// NumLabels returns the number of labels b predicts per token: NumLabels for
+// a MultiLabelBackend, and 1 otherwise.
+func NumLabels(b Backend) int {
+	if m, ok := b.(MultiLabelBackend); ok {
+		return m.NumLabels()
+	}
+	return 1
+}
+
+// InferBatchLabels returns the logits of every label predicted by b, in the
+// layout described by MultiLabelBackend. For other backends it is equivalent
+// to InferBatch.
+func InferBatchLabels(ctx context.Context, b Backend, batch [][]int64, padID int64) ([][]float32, error) {
+	if m, ok := b.(MultiLabelBackend); ok {
+		return m.InferBatchLabels(ctx, batch, padID)
+	}
+	return b.InferBatch(ctx, batch, padID)
+}
+
+// Compile-time interface checks.
+var (
+	_ MultiLabelBackend = (*Session)(nil)
+	_ MultiLabelBackend = (*NativeModel)(nil)
+)
@@ -0,0 +1,487 @@
+package inference
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// NativeConfig describes details of a model's architecture that cannot be
+// inferred from its weights. Zero values select the XLM-RoBERTa defaults.
+type NativeConfig struct {
+	// NumHeads is the number of attention heads (default: hidden size / 64).
+	NumHeads int
+
+	// LayerNormEps is the layer normalization epsilon (default: 1e-5).
+	LayerNormEps float32
+
+	// Lookahead limits each token to attending at most this many tokens to
+	// its right, as in SaT models trained with limited lookahead
+	// (default: 0, unlimited).
+	Lookahead int
+
+	// Adapter, if set, is merged into the weights when the model is loaded.
+	Adapter *Adapter
+}
+
+// NativeModel runs the SaT XLM-RoBERTa encoder in pure Go, without ONNX
+// Runtime. Its weights are immutable after loading, so a single NativeModel
+// is safe for concurrent use and may be shared by every slot of a Pool.
+type NativeModel struct {
+	hidden    int
+	numHeads  int
+	eps       float32
+	lookahead int
+	numLabels int
+
+	wordEmbeddings     tensorData // [vocab, hidden], decoded row by row
+	positionEmbeddings []float32  // [positions, hidden]
+	tokenTypeEmbedding []float32  // [hidden], token type 0
+	embeddingNorm      layerNorm
+	layers             []encoderLayer
+	classifier         linear
+}
+
+// linear is a dense layer with PyTorch weight layout [out, in].
+type linear struct {
+	weight []float32
+	bias   []float32
+	in     int
+	out    int
+}
+
+// layerNorm holds layer normalization parameters.
+type layerNorm struct {
+	weight []float32
+	bias   []float32
+}
+
+// encoderLayer holds the weights of one transformer encoder layer.
+type encoderLayer struct {
+	query, key, value linear
+	attentionOutput   linear
+	attentionNorm     layerNorm
+	intermediate      linear
+	output            linear
+	outputNorm        layerNorm
+}
+
+// LoadNativeModel loads model weights for the pure Go backend. Files ending
+// in .safetensors are read as safetensors checkpoints; anything else is read
+// as an ONNX model whose initializers carry the PyTorch parameter names
+// (e.g. "roberta.encoder.layer.0.attention.self.query.weight").
+func LoadNativeModel(modelPath string, cfg NativeConfig) (*NativeModel, error) {
+	data, err := os.ReadFile(modelPath)
+	if err != nil {
+		return nil, fmt.Errorf("model file: %w", err)
+	}
+
+	return loadNativeModel(data, strings.EqualFold(filepath.Ext(modelPath), ".safetensors"), cfg)
+}
+
+// LoadNativeModelFromBytes loads model weights for the pure Go backend from
+// the contents of a safetensors or ONNX file. The format is detected from the
+// data. The returned model references data, which must not be modified.
+func LoadNativeModelFromBytes(data []byte, cfg NativeConfig) (*NativeModel, error) {
+	return loadNativeModel(data, isSafetensors(data), cfg)
+}
+
+// loadNativeModel decodes weights from data in the given format.
+func loadNativeModel(data []byte, safetensors bool, cfg NativeConfig) (*NativeModel, error) {
+	var (
+		tensors map[string]tensorData
+		err     error
+	)
+	if safetensors {
+		tensors, err = loadSafetensors(data)
+	} else {
+		tensors, err = loadONNXInitializers(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !safetensors && !hasPyTorchNames(tensors) {
+		// Exporters name transposed weights after their nodes, e.g.
+		// "onnx::MatMul_1234", and optimizers fuse them further
+		return nil, fmt.Errorf("%w: ONNX initializers are not named after the PyTorch parameters; load the model's safetensors checkpoint instead", ErrInvalidWeights)
+	}
+	if cfg.Adapter != nil {
+		if tensors, err = cfg.Adapter.apply(tensors); err != nil {
+			return nil, err
+		}
+	}
+
+	return newNativeModel(tensors, cfg)
+}
+
+// hasPyTorchNames reports whether tensors holds the word embeddings under
+// their PyTorch parameter name.
+func hasPyTorchNames(tensors map[string]tensorData) bool {
+	for _, prefix := range []string{"", "roberta."} {
+		if _, ok := tensors[prefix+"embeddings.word_embeddings.weight"]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// newNativeModel assembles a model from named weight tensors.
+func newNativeModel(tensors map[string]tensorData, cfg NativeConfig) (*NativeModel, error) {
+	// Checkpoints for token classification nest the encoder under "roberta."
+	prefix := ""
+	if _, ok := tensors["roberta.embeddings.word_embeddings.weight"]; ok {
+		prefix = "roberta."
+	}
+
+	w := weightReader{tensors: tensors}
+	m := &NativeModel{
+		eps:       cfg.LayerNormEps,
+		lookahead: cfg.Lookahead,
+	}
+	if m.eps == 0 {
+		m.eps = 1e-5
+	}
+
+	m.wordEmbeddings = w.tensor(prefix+"embeddings.word_embeddings.weight", -1, -1)
+	if w.err != nil {
+		return nil, w.err
+	}
+	m.hidden = m.wordEmbeddings.shape[1]
+
+	m.numHeads = cfg.NumHeads
+	if m.numHeads == 0 {
+		m.numHeads = max(1, m.hidden/64)
+	}
+	if m.hidden%m.numHeads != 0 {
+		return nil, fmt.Errorf("%w: hidden size %d not divisible by %d heads", ErrInvalidWeights, m.hidden, m.numHeads)
+	}
+
+	m.positionEmbeddings = w.tensor(prefix+"embeddings.position_embeddings.weight", -1, m.hidden).float32s()
+	if _, ok := tensors[prefix+"embeddings.token_type_embeddings.weight"]; ok {
+		tokenTypes := w.tensor(prefix+"embeddings.token_type_embeddings.weight", -1, m.hidden)
+		if tokenTypes.shape[0] > 0 {
+			m.tokenTypeEmbedding = tokenTypes.float32s()[:m.hidden]
+		}
+	}
+	m.embeddingNorm = w.layerNorm(prefix+"embeddings.LayerNorm", m.hidden)
+
+	for i := 0; ; i++ {
+		p := fmt.Sprintf("%sencoder.layer.%d.", prefix, i)
+		if _, ok := tensors[p+"attention.self.query.weight"]; !ok {
+			break
+		}
+		layer := encoderLayer{
+			query:           w.linear(p+"attention.self.query", m.hidden, m.hidden),
+			key:             w.linear(p+"attention.self.key", m.hidden, m.hidden),
+			value:           w.linear(p+"attention.self.value", m.hidden, m.hidden),
+			attentionOutput: w.linear(p+"attention.output.dense", m.hidden, m.hidden),
+			attentionNorm:   w.layerNorm(p+"attention.output.LayerNorm", m.hidden),
+			intermediate:    w.linear(p+"intermediate.dense", m.hidden, -1),
+			outputNorm:      w.layerNorm(p+"output.LayerNorm", m.hidden),
+		}
+		layer.output = w.linear(p+"output.dense", layer.intermediate.out, m.hidden)
+		m.layers = append(m.layers, layer)
+	}
+
+	m.classifier = w.linear("classifier", m.hidden, -1)
+	if w.err != nil {
+		return nil, w.err
+	}
+	m.numLabels = m.classifier.out
+
+	return m, nil
+}
+
+// weightReader looks up named tensors, recording the first error so that a
+// model can be assembled without checking every lookup.
+type weightReader struct {
+	tensors map[string]tensorData
+	err     error
+}
+
+// tensor returns the 2-D tensor name, checking its dimensions where they are
+// not -1. One-dimensional tensors are accepted as [1, n]. After an error it
+// returns a zero tensor so that assembly can continue until the error is
+// checked.
+func (w *weightReader) tensor(name string, rows, cols int) tensorData {
+	placeholder := func() tensorData {
+		shape := []int{max(rows, 0), max(cols, 0)}
+		return tensorData{shape: shape, raw: make([]byte, shape[0]*shape[1]*dtypeF32.size())}
+	}
+
+	if w.err != nil {
+		return placeholder()
+	}
+	t, ok := w.tensors[name]
+	if !ok {
+		w.err = fmt.Errorf("%w: missing tensor %s", ErrInvalidWeights, name)
+		return placeholder()
+	}
+	shape := t.shape
+	if len(shape) == 1 {
+		shape = []int{1, shape[0]}
+	}
+	if len(shape) != 2 || (rows >= 0 && shape[0] != rows) || (cols >= 0 && shape[1] != cols) {
+		w.err = fmt.Errorf("%w: tensor %s has shape %v, want [%d %d]", ErrInvalidWeights, name, t.shape, rows, cols)
+		return placeholder()
+	}
+	t.shape = shape
+	return t
+}
+
+// vector returns the 1-D tensor name of length n.
+func (w *weightReader) vector(name string, n int) []float32 {
+	return w.tensor(name, 1, n).float32s()
+}
+
+// linear returns the dense layer with the given prefix. A negative out takes
+// the output size from the weights.
+func (w *weightReader) linear(prefix string, in, out int) linear {
+	weight := w.tensor(prefix+".weight", out, in)
+	out = weight.shape[0]
+	return linear{
+		weight: weight.float32s(),
+		bias:   w.vector(prefix+".bias", out),
+		in:     in,
+		out:    out,
+	}
+}
+
+// layerNorm returns the layer normalization parameters with the given prefix.
+func (w *weightReader) layerNorm(prefix string, n int) layerNorm {
+	return layerNorm{
+		weight: w.vector(prefix+".weight", n),
+		bias:   w.vector(prefix+".bias", n),
+	}
+}
+
+// MaxSequenceLength returns the longest sequence the model's position
+// embeddings support, excluding the positions reserved for padding.
+func (m *NativeModel) MaxSequenceLength() int {
+	return len(m.positionEmbeddings)/m.hidden - 2
+}
+
+// NumLabels returns the number of labels predicted by the classifier.
+func (m *NativeModel) NumLabels() int {
+	return m.numLabels
+}
+
+// InferBatch returns per-token logits of the first label for each sequence.
+// Each sequence is run on its own, so no padding is required.
+func (m *NativeModel) InferBatch(ctx context.Context, batch [][]int64, padID int64) ([][]float32, error) {
+	results, err := m.InferBatchLabels(ctx, batch, padID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i] = labelColumn(results[i], m.numLabels, 0)
+	}
+	return results, nil
+}
+
+// InferBatchLabels returns per-token logits of every label for each sequence.
+func (m *NativeModel) InferBatchLabels(ctx context.Context, batch [][]int64, padID int64) ([][]float32, error) {
+	results := make([][]float32, len(batch))
+	for i, ids := range batch {
+		logits, err := m.forward(ctx, ids, padID)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = logits
+	}
+	return results, nil
+}
+
+// Close is a no-op; the model holds only Go memory.
+func (m *NativeModel) Close() error {
+	return nil
+}
+
+// errSequenceTooLong indicates a sequence exceeds the position embeddings.
+var errSequenceTooLong = errors.New("inference: sequence exceeds maximum length")
+
+// forward runs the encoder and classifier on one sequence and returns logits
+// of shape [len(ids), numLabels] in row-major order.
+func (m *NativeModel) forward(ctx context.Context, ids []int64, padID int64) ([]float32, error) {
+	n, h := len(ids), m.hidden
+	if n == 0 {
+		return nil, nil
+	}
+	if n > m.MaxSequenceLength() {
+		return nil, fmt.Errorf("%w: %d > %d", errSequenceTooLong, n, m.MaxSequenceLength())
+	}
+
+	// Embeddings. Positions follow RoBERTa: padding tokens use padID and the
+	// others count up from padID+1.
+	vocab := m.wordEmbeddings.shape[0]
+	x := make([]float32, n*h)
+	pos := padID
+	for i, id := range ids {
+		if id < 0 || id >= int64(vocab) {
+			return nil, fmt.Errorf("%w: token ID %d out of range", ErrInvalidWeights, id)
+		}
+		row := x[i*h : (i+1)*h]
+		m.wordEmbeddings.decode(int(id)*h, row)
+
+		p := padID
+		if id != padID {
+			pos++
+			p = pos
+		}
+		addInto(row, m.positionEmbeddings[int(p)*h:(int(p)+1)*h])
+		if m.tokenTypeEmbedding != nil {
+			addInto(row, m.tokenTypeEmbedding)
+		}
+	}
+	m.embeddingNorm.apply(x, m.eps)
+
+	for i := range m.layers {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+		x = m.layers[i].forward(x, n, m.numHeads, m.lookahead, m.eps)
+	}
+
+	return m.classifier.apply(x, n), nil
+}
+
+// forward applies the encoder layer to x of shape [n, hidden].
+func (l *encoderLayer) forward(x []float32, n, numHeads, lookahead int, eps float32) []float32 {
+	h := l.query.in
+	headDim := h / numHeads
+	scale := float32(1 / math.Sqrt(float64(headDim)))
+
+	q := l.query.apply(x, n)
+	k := l.key.apply(x, n)
+	v := l.value.apply(x, n)
+
+	attended := make([]float32, n*h)
+	scores := make([]float32, n)
+	for head := 0; head < numHeads; head++ {
+		off := head * headDim
+		for i := 0; i < n; i++ {
+			qi := q[i*h+off : i*h+off+headDim]
+
+			// Attend to every key unless lookahead limits the right context
+			last := n - 1
+			if lookahead > 0 {
+				last = min(last, i+lookahead)
+			}
+			for j := 0; j <= last; j++ {
+				scores[j] = dot(qi, k[j*h+off:j*h+off+headDim]) * scale
+			}
+			softmax(scores[:last+1])
+
+			ci := attended[i*h+off : i*h+off+headDim]
+			for j := 0; j <= last; j++ {
+				axpy(scores[j], v[j*h+off:j*h+off+headDim], ci)
+			}
+		}
+	}
+
+	attn := l.attentionOutput.apply(attended, n)
+	addInto(attn, x)
+	l.attentionNorm.apply(attn, eps)
+
+	inter := l.intermediate.apply(attn, n)
+	for i, val := range inter {
+		inter[i] = gelu(val)
+	}
+	out := l.output.apply(inter, n)
+	addInto(out, attn)
+	l.outputNorm.apply(out, eps)
+
+	return out
+}
+
+// apply computes x·Wᵀ + b for x of shape [n, in], returning [n, out].
+func (l *linear) apply(x []float32, n int) []float32 {
+	y := make([]float32, n*l.out)
+	for i := 0; i < n; i++ {
+		xi := x[i*l.in : (i+1)*l.in]
+		yi := y[i*l.out : (i+1)*l.out]
+		for o := range yi {
+			yi[o] = dot(xi, l.weight[o*l.in:(o+1)*l.in]) + l.bias[o]
+		}
+	}
+	return y
+}
+
+// apply normalizes each row of x in place.
+func (ln *layerNorm) apply(x []float32, eps float32) {
+	h := len(ln.weight)
+	for off := 0; off < len(x); off += h {
+		row := x[off : off+h]
+
+		var mean float64
+		for _, v := range row {
+			mean += float64(v)
+		}
+		mean /= float64(h)
+
+		var variance float64
+		for _, v := range row {
+			d := float64(v) - mean
+			variance += d * d
+		}
+		variance /= float64(h)
+
+		inv := 1 / math.Sqrt(variance+float64(eps))
+		for i, v := range row {
+			row[i] = float32((float64(v)-mean)*inv)*ln.weight[i] + ln.bias[i]
+		}
+	}
+}
+
+// dot returns the dot product of a and b, which must have equal length.
+func dot(a, b []float32) float32 {
+	b = b[:len(a)]
+	var sum float32
+	for i, v := range a {
+		sum += v * b[i]
+	}
+	return sum
+}
+
+// axpy computes y += alpha*x.
+func axpy(alpha float32, x, y []float32) {
+	y = y[:len(x)]
+	for i, v := range x {
+		y[i] += alpha * v
+	}
+}
+
+// addInto computes dst += src element-wise.
+func addInto(dst, src []float32) {
+	src = src[:len(dst)]
+	for i := range dst {
+		dst[i] += src[i]
+	}
+}
+
+// softmax normalizes x in place.
+func softmax(x []float32) {
+	maxVal := float32(math.Inf(-1))
+	for _, v := range x {
+		maxVal = max(maxVal, v)
+	}
+	var sum float32
+	for i, v := range x {
+		x[i] = float32(math.Exp(float64(v - maxVal)))
+		sum += x[i]
+	}
+	for i := range x {
+		x[i] /= sum
+	}
+}
+
+// gelu is the exact (erf-based) GELU activation used by XLM-RoBERTa.
+func gelu(x float32) float32 {
+	return float32(0.5 * float64(x) * (1 + math.Erf(float64(x)/math.Sqrt2)))
+}
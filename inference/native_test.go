@@ -0,0 +1,580 @@
+package inference
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// testTensor is a float32 weight used to build small test models.
+type testTensor struct {
+	shape []int
+	data  []float32
+}
+
+// tinyModelWeights returns random weights for a small XLM-RoBERTa-style
+// token classifier with the given number of encoder layers.
+func tinyModelWeights(layers int) map[string]testTensor {
+	const (
+		vocab     = 32
+		positions = 40
+		hidden    = 8
+		inter     = 16
+	)
+	rng := rand.New(rand.NewPCG(3, 5))
+	random := func(shape ...int) testTensor {
+		n := 1
+		for _, d := range shape {
+			n *= d
+		}
+		data := make([]float32, n)
+		for i := range data {
+			data[i] = rng.Float32() - 0.5
+		}
+		return testTensor{shape: shape, data: data}
+	}
+
+	w := map[string]testTensor{
+		"roberta.embeddings.word_embeddings.weight":       random(vocab, hidden),
+		"roberta.embeddings.position_embeddings.weight":   random(positions, hidden),
+		"roberta.embeddings.token_type_embeddings.weight": random(1, hidden),
+		"roberta.embeddings.LayerNorm.weight":             random(hidden),
+		"roberta.embeddings.LayerNorm.bias":               random(hidden),
+		"classifier.weight":                               random(2, hidden),
+		"classifier.bias":                                 random(2),
+	}
+	for i := 0; i < layers; i++ {
+		p := "roberta.encoder.layer." + string(rune('0'+i)) + "."
+		for _, name := range []string{"attention.self.query", "attention.self.key", "attention.self.value", "attention.output.dense"} {
+			w[p+name+".weight"] = random(hidden, hidden)
+			w[p+name+".bias"] = random(hidden)
+		}
+		w[p+"intermediate.dense.weight"] = random(inter, hidden)
+		w[p+"intermediate.dense.bias"] = random(inter)
+		w[p+"output.dense.weight"] = random(hidden, inter)
+		w[p+"output.dense.bias"] = random(hidden)
+		for _, name := range []string{"attention.output.LayerNorm", "output.LayerNorm"} {
+			w[p+name+".weight"] = random(hidden)
+			w[p+name+".bias"] = random(hidden)
+		}
+	}
+	return w
+}
+
+// sortedTensorNames returns the names of w in lexical order.
+func sortedTensorNames(w map[string]testTensor) []string {
+	names := make([]string, 0, len(w))
+	for name := range w {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// encodeSafetensors serializes w as a safetensors file with float32 or
+// float16 elements.
+func encodeSafetensors(t *testing.T, w map[string]testTensor, half bool) []byte {
+	t.Helper()
+
+	header := map[string]any{"__metadata__": map[string]string{"format": "pt"}}
+	var body []byte
+	for _, name := range sortedTensorNames(w) {
+		tensor := w[name]
+		start := len(body)
+		dtype := "F32"
+		for _, v := range tensor.data {
+			if half {
+				dtype = "F16"
+				body = binary.LittleEndian.AppendUint16(body, float32ToFloat16(v))
+			} else {
+				body = binary.LittleEndian.AppendUint32(body, math.Float32bits(v))
+			}
+		}
+		header[name] = map[string]any{"dtype": dtype, "shape": tensor.shape, "data_offsets": []int{start, len(body)}}
+	}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		t.Fatalf("marshal header: %v", err)
+	}
+	data := binary.LittleEndian.AppendUint64(nil, uint64(len(headerJSON)))
+	data = append(data, headerJSON...)
+	return append(data, body...)
+}
+
+// encodeONNX serializes w as the initializers of a minimal ONNX model. Every
+// other tensor uses float_data instead of raw_data to cover both encodings.
+func encodeONNX(w map[string]testTensor) []byte {
+	var graph []byte
+	for i, name := range sortedTensorNames(w) {
+		tensor := w[name]
+
+		var tp []byte
+		for _, d := range tensor.shape {
+			tp = protowire.AppendTag(tp, onnxTensorDims, protowire.VarintType)
+			tp = protowire.AppendVarint(tp, uint64(d))
+		}
+		tp = protowire.AppendTag(tp, onnxTensorDataType, protowire.VarintType)
+		tp = protowire.AppendVarint(tp, onnxTypeFloat)
+		tp = protowire.AppendTag(tp, onnxTensorName, protowire.BytesType)
+		tp = protowire.AppendString(tp, name)
+
+		var raw []byte
+		for _, v := range tensor.data {
+			raw = binary.LittleEndian.AppendUint32(raw, math.Float32bits(v))
+		}
+		if i%2 == 0 {
+			tp = protowire.AppendTag(tp, onnxTensorRawData, protowire.BytesType)
+		} else {
+			tp = protowire.AppendTag(tp, onnxTensorFloatData, protowire.BytesType)
+		}
+		tp = protowire.AppendBytes(tp, raw)
+
+		graph = protowire.AppendTag(graph, onnxGraphInitializer, protowire.BytesType)
+		graph = protowire.AppendBytes(graph, tp)
+	}
+
+	var model []byte
+	model = protowire.AppendTag(model, 1, protowire.VarintType) // ir_version
+	model = protowire.AppendVarint(model, 8)
+	model = protowire.AppendTag(model, onnxModelGraph, protowire.BytesType)
+	return protowire.AppendBytes(model, graph)
+}
+
+// float32ToFloat16 converts a float32 to IEEE half precision by truncation,
+// which is sufficient for the small values used in tests.
+func float32ToFloat16(f float32) uint16 {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int((bits>>23)&0xFF) - 127 + 15
+	frac := uint16(bits>>13) & 0x3FF
+	if exp <= 0 {
+		return sign
+	}
+	return sign | uint16(exp)<<10 | frac
+}
+
+func writeTestFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestLoadSafetensors(t *testing.T) {
+	w := map[string]testTensor{
+		"a": {shape: []int{2, 2}, data: []float32{1, -2, 0.5, 0}},
+		"b": {shape: []int{3}, data: []float32{0.25, 4, -1}},
+	}
+
+	for _, half := range []bool{false, true} {
+		tensors, err := loadSafetensors(encodeSafetensors(t, w, half))
+		if err != nil {
+			t.Fatalf("loadSafetensors(half=%v) failed: %v", half, err)
+		}
+		for name, want := range w {
+			got := tensors[name].float32s()
+			if len(got) != len(want.data) {
+				t.Fatalf("%s: got %d elements, want %d", name, len(got), len(want.data))
+			}
+			for i := range got {
+				if got[i] != want.data[i] {
+					t.Errorf("%s[%d] (half=%v) = %v, want %v", name, i, half, got[i], want.data[i])
+				}
+			}
+		}
+	}
+}
+
+func TestLoadSafetensors_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"too short", []byte{1, 2}},
+		{"header too long", binary.LittleEndian.AppendUint64(nil, 1000)},
+		{"bad json", append(binary.LittleEndian.AppendUint64(nil, 3), "{x}"...)},
+		{"bad offsets", append(binary.LittleEndian.AppendUint64(nil, 52),
+			`{"a":{"dtype":"F32","shape":[2],"data_offsets":[0,4]}}`...)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := loadSafetensors(tc.data)
+			if !errors.Is(err, ErrInvalidWeights) {
+				t.Errorf("expected ErrInvalidWeights, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadONNXInitializers(t *testing.T) {
+	w := tinyModelWeights(1)
+	tensors, err := loadONNXInitializers(encodeONNX(w))
+	if err != nil {
+		t.Fatalf("loadONNXInitializers failed: %v", err)
+	}
+	if len(tensors) != len(w) {
+		t.Fatalf("got %d initializers, want %d", len(tensors), len(w))
+	}
+	for name, want := range w {
+		got := tensors[name].float32s()
+		for i := range want.data {
+			if got[i] != want.data[i] {
+				t.Fatalf("%s[%d] = %v, want %v", name, i, got[i], want.data[i])
+			}
+		}
+	}
+}
+
+func TestLoadONNXInitializers_NoGraph(t *testing.T) {
+	_, err := loadONNXInitializers(protowire.AppendVarint(protowire.AppendTag(nil, 1, protowire.VarintType), 8))
+	if !errors.Is(err, ErrInvalidWeights) {
+		t.Errorf("expected ErrInvalidWeights, got: %v", err)
+	}
+}
+
+func TestLoadNativeModel_FileNotFound(t *testing.T) {
+	_, err := LoadNativeModel("../testdata/nonexistent.safetensors", NativeConfig{})
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected os.ErrNotExist, got: %v", err)
+	}
+}
+
+func TestLoadNativeModel_MissingTensor(t *testing.T) {
+	w := tinyModelWeights(1)
+	delete(w, "classifier.bias")
+
+	path := writeTestFile(t, "model.safetensors", encodeSafetensors(t, w, false))
+	_, err := LoadNativeModel(path, NativeConfig{NumHeads: 2})
+	if !errors.Is(err, ErrInvalidWeights) {
+		t.Errorf("expected ErrInvalidWeights, got: %v", err)
+	}
+}
+
+func TestLoadNativeModel_RenamedInitializers(t *testing.T) {
+	w := tinyModelWeights(1)
+	for i, name := range sortedTensorNames(w) {
+		w[fmt.Sprintf("onnx::MatMul_%d", i)] = w[name]
+		delete(w, name)
+	}
+
+	_, err := LoadNativeModelFromBytes(encodeONNX(w), NativeConfig{NumHeads: 2})
+	if !errors.Is(err, ErrInvalidWeights) || !strings.Contains(err.Error(), "safetensors") {
+		t.Errorf("expected ErrInvalidWeights pointing to safetensors, got: %v", err)
+	}
+}
+
+func TestNativeModel_NoLayers(t *testing.T) {
+	w := tinyModelWeights(0)
+	path := writeTestFile(t, "model.safetensors", encodeSafetensors(t, w, false))
+	model, err := LoadNativeModel(path, NativeConfig{NumHeads: 2})
+	if err != nil {
+		t.Fatalf("LoadNativeModel failed: %v", err)
+	}
+
+	// Without encoder layers the logits are classifier(LayerNorm(embeddings))
+	ids := []int64{0, 7, 2}
+	got, err := model.InferBatch(context.Background(), [][]int64{ids}, 1)
+	if err != nil {
+		t.Fatalf("InferBatch failed: %v", err)
+	}
+
+	const hidden = 8
+	word := w["roberta.embeddings.word_embeddings.weight"].data
+	pos := w["roberta.embeddings.position_embeddings.weight"].data
+	typ := w["roberta.embeddings.token_type_embeddings.weight"].data
+	lnW := w["roberta.embeddings.LayerNorm.weight"].data
+	lnB := w["roberta.embeddings.LayerNorm.bias"].data
+	clsW := w["classifier.weight"].data
+	clsB := w["classifier.bias"].data
+
+	for i, id := range ids {
+		x := make([]float64, hidden)
+		var mean float64
+		for k := range x {
+			x[k] = float64(word[int(id)*hidden+k] + pos[(i+2)*hidden+k] + typ[k])
+			mean += x[k]
+		}
+		mean /= hidden
+		var variance float64
+		for k := range x {
+			variance += (x[k] - mean) * (x[k] - mean)
+		}
+		variance /= hidden
+
+		want := float64(clsB[0])
+		for k := range x {
+			norm := (x[k]-mean)/math.Sqrt(variance+1e-5)*float64(lnW[k]) + float64(lnB[k])
+			want += norm * float64(clsW[k])
+		}
+		if diff := math.Abs(float64(got[0][i]) - want); diff > 1e-4 {
+			t.Errorf("token %d: logit %v, want %v", i, got[0][i], want)
+		}
+	}
+}
+
+func TestNativeModel_FormatsAgree(t *testing.T) {
+	w := tinyModelWeights(2)
+	cfg := NativeConfig{NumHeads: 2}
+
+	fromSafetensors, err := LoadNativeModel(writeTestFile(t, "model.safetensors", encodeSafetensors(t, w, false)), cfg)
+	if err != nil {
+		t.Fatalf("loading safetensors: %v", err)
+	}
+	fromONNX, err := LoadNativeModel(writeTestFile(t, "model.onnx", encodeONNX(w)), cfg)
+	if err != nil {
+		t.Fatalf("loading ONNX: %v", err)
+	}
+
+	batch := [][]int64{{0, 5, 9, 13, 2}, {0, 4, 2}}
+	ctx := context.Background()
+	a, err := fromSafetensors.InferBatch(ctx, batch, 1)
+	if err != nil {
+		t.Fatalf("InferBatch failed: %v", err)
+	}
+	b, err := fromONNX.InferBatch(ctx, batch, 1)
+	if err != nil {
+		t.Fatalf("InferBatch failed: %v", err)
+	}
+
+	for i := range batch {
+		if len(a[i]) != len(batch[i]) {
+			t.Fatalf("row %d: got %d logits, want %d", i, len(a[i]), len(batch[i]))
+		}
+		for j := range a[i] {
+			if a[i][j] != b[i][j] {
+				t.Errorf("row %d token %d: safetensors %v, ONNX %v", i, j, a[i][j], b[i][j])
+			}
+		}
+	}
+}
+
+func TestLoadNativeModelFromBytes(t *testing.T) {
+	w := tinyModelWeights(1)
+	cfg := NativeConfig{NumHeads: 2}
+	batch := [][]int64{{0, 5, 9, 2}}
+	ctx := context.Background()
+
+	fromFile, err := LoadNativeModel(writeTestFile(t, "model.safetensors", encodeSafetensors(t, w, false)), cfg)
+	if err != nil {
+		t.Fatalf("LoadNativeModel failed: %v", err)
+	}
+	want, err := fromFile.InferBatch(ctx, batch, 1)
+	if err != nil {
+		t.Fatalf("InferBatch failed: %v", err)
+	}
+
+	formats := map[string][]byte{
+		"safetensors": encodeSafetensors(t, w, false),
+		"onnx":        encodeONNX(w),
+	}
+	for name, data := range formats {
+		t.Run(name, func(t *testing.T) {
+			model, err := LoadNativeModelFromBytes(data, cfg)
+			if err != nil {
+				t.Fatalf("LoadNativeModelFromBytes failed: %v", err)
+			}
+			got, err := model.InferBatch(ctx, batch, 1)
+			if err != nil {
+				t.Fatalf("InferBatch failed: %v", err)
+			}
+			for j := range want[0] {
+				if got[0][j] != want[0][j] {
+					t.Errorf("token %d: got %v, want %v", j, got[0][j], want[0][j])
+				}
+			}
+		})
+	}
+}
+
+func TestNativeModel_BatchMatchesSingle(t *testing.T) {
+	model, err := LoadNativeModel(writeTestFile(t, "model.safetensors", encodeSafetensors(t, tinyModelWeights(1), false)), NativeConfig{NumHeads: 2})
+	if err != nil {
+		t.Fatalf("LoadNativeModel failed: %v", err)
+	}
+
+	ctx := context.Background()
+	batch := [][]int64{{0, 5, 9, 13, 2}, {0, 4, 2}}
+	batched, err := model.InferBatch(ctx, batch, 1)
+	if err != nil {
+		t.Fatalf("InferBatch failed: %v", err)
+	}
+	for i, ids := range batch {
+		single, err := model.InferBatch(ctx, [][]int64{ids}, 1)
+		if err != nil {
+			t.Fatalf("InferBatch failed: %v", err)
+		}
+		for j := range ids {
+			if batched[i][j] != single[0][j] {
+				t.Errorf("row %d token %d: batched %v, single %v", i, j, batched[i][j], single[0][j])
+			}
+		}
+	}
+}
+
+func TestNativeModel_InferBatchLabels(t *testing.T) {
+	model, err := LoadNativeModel(writeTestFile(t, "model.safetensors", encodeSafetensors(t, tinyModelWeights(1), false)), NativeConfig{NumHeads: 2})
+	if err != nil {
+		t.Fatalf("LoadNativeModel failed: %v", err)
+	}
+	if got := NumLabels(model); got != 2 {
+		t.Fatalf("NumLabels = %d, want 2", got)
+	}
+
+	ctx := context.Background()
+	batch := [][]int64{{0, 5, 9, 13, 2}}
+	labels, err := InferBatchLabels(ctx, model, batch, 1)
+	if err != nil {
+		t.Fatalf("InferBatchLabels failed: %v", err)
+	}
+	first, err := model.InferBatch(ctx, batch, 1)
+	if err != nil {
+		t.Fatalf("InferBatch failed: %v", err)
+	}
+
+	if len(labels[0]) != 2*len(batch[0]) {
+		t.Fatalf("got %d label logits, want %d", len(labels[0]), 2*len(batch[0]))
+	}
+	for j := range batch[0] {
+		if labels[0][j*2] != first[0][j] {
+			t.Errorf("token %d: label 0 = %v, InferBatch = %v", j, labels[0][j*2], first[0][j])
+		}
+	}
+}
+
+func TestNativeModel_Lookahead(t *testing.T) {
+	model, err := LoadNativeModel(writeTestFile(t, "model.safetensors", encodeSafetensors(t, tinyModelWeights(1), false)), NativeConfig{NumHeads: 2, Lookahead: 1})
+	if err != nil {
+		t.Fatalf("LoadNativeModel failed: %v", err)
+	}
+
+	// With one layer and lookahead 1, token 0 only sees tokens 0 and 1
+	ctx := context.Background()
+	out, err := model.InferBatch(ctx, [][]int64{{0, 5, 9, 13}, {0, 5, 20, 21}}, 1)
+	if err != nil {
+		t.Fatalf("InferBatch failed: %v", err)
+	}
+	if out[0][0] != out[1][0] {
+		t.Errorf("token 0 depends on tokens beyond lookahead: %v != %v", out[0][0], out[1][0])
+	}
+	if out[0][1] == out[1][1] {
+		t.Error("token 1 should depend on token 2 within lookahead")
+	}
+}
+
+func TestNativeModel_Errors(t *testing.T) {
+	model, err := LoadNativeModel(writeTestFile(t, "model.safetensors", encodeSafetensors(t, tinyModelWeights(1), false)), NativeConfig{NumHeads: 2})
+	if err != nil {
+		t.Fatalf("LoadNativeModel failed: %v", err)
+	}
+	ctx := context.Background()
+
+	if _, err := model.InferBatch(ctx, [][]int64{{0, 99}}, 1); !errors.Is(err, ErrInvalidWeights) {
+		t.Errorf("expected ErrInvalidWeights for out-of-range ID, got: %v", err)
+	}
+
+	tooLong := make([]int64, model.MaxSequenceLength()+1)
+	if _, err := model.InferBatch(ctx, [][]int64{tooLong}, 1); err == nil {
+		t.Error("expected error for sequence longer than MaxSequenceLength")
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := model.InferBatch(cancelled, [][]int64{{0, 5, 2}}, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got: %v", err)
+	}
+}
+
+func TestNativeModel_ParityWithORT(t *testing.T) {
+	weightsPath := "../testdata/model.safetensors"
+	modelPath := "../testdata/model_optimized.onnx"
+
+	// Skip if model files don't exist
+	for _, path := range []string{weightsPath, modelPath} {
+		if _, err := os.Stat(path); err != nil {
+			t.Skipf("Skipping: model not available at %s", path)
+		}
+	}
+
+	session, err := NewSession(modelPath)
+	if err != nil {
+		if isORTUnavailableError(err) {
+			t.Skipf("Skipping: ONNX runtime not available: %v", err)
+		}
+		t.Fatalf("NewSession failed: %v", err)
+	}
+	defer func() { _ = session.Close() }()
+
+	native, err := LoadNativeModel(weightsPath, NativeConfig{})
+	if err != nil {
+		t.Fatalf("LoadNativeModel failed: %v", err)
+	}
+
+	ctx := context.Background()
+	batch := [][]int64{
+		{0, 35378, 8, 38, 3714, 43033, 5, 2}, // <s> Hello , I like cats . </s>
+		{0, 35378, 5, 2},                     // <s> Hello . </s>
+	}
+	want, err := session.InferBatch(ctx, batch, 1)
+	if err != nil {
+		t.Fatalf("ORT InferBatch failed: %v", err)
+	}
+	got, err := native.InferBatch(ctx, batch, 1)
+	if err != nil {
+		t.Fatalf("native InferBatch failed: %v", err)
+	}
+
+	// The ORT model runs in float16, so allow for its rounding error
+	for i := range batch {
+		for j := range batch[i] {
+			if diff := math.Abs(float64(got[i][j] - want[i][j])); diff > 0.1 {
+				t.Errorf("row %d token %d: native %v, ORT %v", i, j, got[i][j], want[i][j])
+			}
+		}
+	}
+}
+
+func TestGelu(t *testing.T) {
+	tests := []struct {
+		input, expected float32
+	}{
+		{0, 0},
+		{1, 0.8413},
+		{-1, -0.1587},
+		{3, 2.9960},
+	}
+	for _, tt := range tests {
+		if got := gelu(tt.input); math.Abs(float64(got-tt.expected)) > 1e-3 {
+			t.Errorf("gelu(%v) = %v, want ~%v", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestSoftmax(t *testing.T) {
+	x := []float32{1, 2, 3, 1000}
+	softmax(x)
+	var sum float32
+	for _, v := range x {
+		sum += v
+	}
+	if math.Abs(float64(sum-1)) > 1e-5 {
+		t.Errorf("softmax sums to %v, want 1", sum)
+	}
+	if x[3] < 0.999 {
+		t.Errorf("softmax of large input = %v, want ~1", x[3])
+	}
+}
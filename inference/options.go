@@ -0,0 +1,143 @@
+package inference
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// GraphOptimizationLevel selects how much ONNX Runtime rewrites the model
+// graph when a session is created.
+type GraphOptimizationLevel int
+
+// Graph optimization levels. GraphOptimizationDefault keeps the ONNX Runtime
+// default, which enables all optimizations.
+const (
+	GraphOptimizationDefault GraphOptimizationLevel = iota
+	GraphOptimizationDisabled
+	GraphOptimizationBasic
+	GraphOptimizationExtended
+	GraphOptimizationAll
+)
+
+// ExecutionMode selects whether ONNX Runtime runs independent graph nodes
+// sequentially or in parallel.
+type ExecutionMode int
+
+// Execution modes. ExecutionModeDefault keeps the ONNX Runtime default, which
+// is sequential.
+const (
+	ExecutionModeDefault ExecutionMode = iota
+	ExecutionModeSequential
+	ExecutionModeParallel
+)
+
+// SessionConfig configures ONNX Runtime sessions. The zero value keeps the
+// ONNX Runtime defaults.
+type SessionConfig struct {
+	// IntraOpThreads is the number of threads used to parallelize a single
+	// operator. Zero lets ONNX Runtime choose, typically one per core.
+	IntraOpThreads int
+
+	// InterOpThreads is the number of threads used to run independent
+	// operators in parallel when ExecutionMode is ExecutionModeParallel.
+	// Zero lets ONNX Runtime choose.
+	InterOpThreads int
+
+	// GraphOptimization is the graph optimization level.
+	GraphOptimization GraphOptimizationLevel
+
+	// ExecutionMode selects sequential or parallel operator execution.
+	ExecutionMode ExecutionMode
+
+	// PreoptimizedModelPath names a copy of the model that has already been
+	// optimized, e.g. with onnxruntime's SessionOptions.optimized_model_filepath
+	// in Python. If the file exists it is loaded in place of the model,
+	// whether the pool was given a model path or model data, and graph
+	// optimization is skipped, which shortens session creation. If it does
+	// not exist the model is loaded and optimized as usual. The file is only
+	// read: the Go binding cannot save an optimized model, so it is never
+	// written here.
+	PreoptimizedModelPath string
+}
+
+// preoptimized reports whether the preoptimized model named by c exists.
+func (c SessionConfig) preoptimized() (bool, error) {
+	if c.PreoptimizedModelPath == "" {
+		return false, nil
+	}
+	if _, err := os.Stat(c.PreoptimizedModelPath); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("preoptimized model file: %w", err)
+	}
+	return true, nil
+}
+
+// apply sets the configured values on options.
+func (c SessionConfig) apply(options *ort.SessionOptions) error {
+	if c.IntraOpThreads < 0 || c.InterOpThreads < 0 {
+		return fmt.Errorf("invalid thread counts: intra-op %d, inter-op %d", c.IntraOpThreads, c.InterOpThreads)
+	}
+	if c.IntraOpThreads > 0 {
+		if err := options.SetIntraOpNumThreads(c.IntraOpThreads); err != nil {
+			return fmt.Errorf("setting intra-op threads: %w", err)
+		}
+	}
+	if c.InterOpThreads > 0 {
+		if err := options.SetInterOpNumThreads(c.InterOpThreads); err != nil {
+			return fmt.Errorf("setting inter-op threads: %w", err)
+		}
+	}
+
+	if c.GraphOptimization != GraphOptimizationDefault {
+		level, err := c.GraphOptimization.ort()
+		if err != nil {
+			return err
+		}
+		if err := options.SetGraphOptimizationLevel(level); err != nil {
+			return fmt.Errorf("setting graph optimization level: %w", err)
+		}
+	}
+
+	if c.ExecutionMode != ExecutionModeDefault {
+		mode, err := c.ExecutionMode.ort()
+		if err != nil {
+			return err
+		}
+		if err := options.SetExecutionMode(mode); err != nil {
+			return fmt.Errorf("setting execution mode: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ort converts l to the ONNX Runtime enum.
+func (l GraphOptimizationLevel) ort() (ort.GraphOptimizationLevel, error) {
+	switch l {
+	case GraphOptimizationDisabled:
+		return ort.GraphOptimizationLevelDisableAll, nil
+	case GraphOptimizationBasic:
+		return ort.GraphOptimizationLevelEnableBasic, nil
+	case GraphOptimizationExtended:
+		return ort.GraphOptimizationLevelEnableExtended, nil
+	case GraphOptimizationAll, GraphOptimizationDefault:
+		return ort.GraphOptimizationLevelEnableAll, nil
+	}
+	return 0, fmt.Errorf("unknown graph optimization level %d", l)
+}
+
+// ort converts m to the ONNX Runtime enum.
+func (m ExecutionMode) ort() (ort.ExecutionMode, error) {
+	switch m {
+	case ExecutionModeSequential, ExecutionModeDefault:
+		return ort.ExecutionModeSequential, nil
+	case ExecutionModeParallel:
+		return ort.ExecutionModeParallel, nil
+	}
+	return 0, fmt.Errorf("unknown execution mode %d", m)
+}
@@ -0,0 +1,113 @@
+package inference
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+func TestSessionConfig_Preoptimized(t *testing.T) {
+	existing := filepath.Join(t.TempDir(), "model.opt.onnx")
+	if err := os.WriteFile(existing, []byte("cached"), 0o600); err != nil {
+		t.Fatalf("write cached model: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want bool
+	}{
+		{"unset", "", false},
+		{"missing", filepath.Join(t.TempDir(), "missing.onnx"), false},
+		{"existing", existing, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SessionConfig{PreoptimizedModelPath: tt.path}.preoptimized()
+			if err != nil {
+				t.Fatalf("preoptimized failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("preoptimized() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGraphOptimizationLevel_ORT(t *testing.T) {
+	tests := []struct {
+		level GraphOptimizationLevel
+		want  ort.GraphOptimizationLevel
+	}{
+		{GraphOptimizationDefault, ort.GraphOptimizationLevelEnableAll},
+		{GraphOptimizationDisabled, ort.GraphOptimizationLevelDisableAll},
+		{GraphOptimizationBasic, ort.GraphOptimizationLevelEnableBasic},
+		{GraphOptimizationExtended, ort.GraphOptimizationLevelEnableExtended},
+		{GraphOptimizationAll, ort.GraphOptimizationLevelEnableAll},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.level.ort()
+		if err != nil {
+			t.Fatalf("level %d: %v", tt.level, err)
+		}
+		if got != tt.want {
+			t.Errorf("level %d = %v, want %v", tt.level, got, tt.want)
+		}
+	}
+
+	if _, err := GraphOptimizationLevel(99).ort(); err == nil {
+		t.Error("expected error for unknown level")
+	}
+}
+
+func TestExecutionMode_ORT(t *testing.T) {
+	tests := []struct {
+		mode ExecutionMode
+		want ort.ExecutionMode
+	}{
+		{ExecutionModeDefault, ort.ExecutionModeSequential},
+		{ExecutionModeSequential, ort.ExecutionModeSequential},
+		{ExecutionModeParallel, ort.ExecutionModeParallel},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.mode.ort()
+		if err != nil {
+			t.Fatalf("mode %d: %v", tt.mode, err)
+		}
+		if got != tt.want {
+			t.Errorf("mode %d = %v, want %v", tt.mode, got, tt.want)
+		}
+	}
+
+	if _, err := ExecutionMode(99).ort(); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
+
+func TestNewSessionWithConfig(t *testing.T) {
+	modelPath := "../testdata/model_optimized.onnx"
+
+	// Skip if model file doesn't exist
+	if _, err := os.Stat(modelPath); err != nil {
+		t.Skipf("Skipping: model not available at %s", modelPath)
+	}
+
+	session, err := NewSessionWithConfig(modelPath, SessionConfig{
+		IntraOpThreads:    1,
+		InterOpThreads:    1,
+		GraphOptimization: GraphOptimizationBasic,
+		ExecutionMode:     ExecutionModeParallel,
+	})
+	if err != nil {
+		if isORTUnavailableError(err) {
+			t.Skipf("Skipping: ONNX runtime not available: %v", err)
+		}
+		t.Fatalf("NewSessionWithConfig failed: %v", err)
+	}
+	defer func() { _ = session.Close() }()
+}
@@ -7,35 +7,77 @@ import (
 	"sync"
 )
 
-// Pool manages a pool of ONNX sessions for concurrent inference.
+// Pool manages a pool of inference backends for concurrent inference.
 type Pool struct {
-	sessions  chan *Session
-	modelPath string
+	sessions  chan Backend
 	size      int
+	maxSeqLen int
+	numLabels int
 	mu        sync.Mutex
 	closed    bool
 }
 
 // NewPool creates a pool of n ONNX sessions.
 func NewPool(modelPath string, size int) (*Pool, error) {
+	return NewPoolWithConfig(modelPath, size, SessionConfig{})
+}
+
+// NewPoolWithConfig creates a pool of n ONNX sessions, each configured with
+// cfg. Every session has its own ONNX Runtime thread pools, so
+// cfg.IntraOpThreads should usually be about runtime.NumCPU() / size.
+func NewPoolWithConfig(modelPath string, size int, cfg SessionConfig) (*Pool, error) {
+	return NewPoolFunc(size, func() (Backend, error) {
+		session, err := NewSessionWithConfig(modelPath, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return session, nil
+	})
+}
+
+// NewPoolFromBytes creates a pool of n ONNX sessions from the contents of a
+// model file.
+func NewPoolFromBytes(data []byte, size int) (*Pool, error) {
+	return NewPoolFromBytesWithConfig(data, size, SessionConfig{})
+}
+
+// NewPoolFromBytesWithConfig creates a pool of n ONNX sessions from the
+// contents of a model file, each configured with cfg.
+func NewPoolFromBytesWithConfig(data []byte, size int, cfg SessionConfig) (*Pool, error) {
+	return NewPoolFunc(size, func() (Backend, error) {
+		session, err := NewSessionFromBytesWithConfig(data, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return session, nil
+	})
+}
+
+// NewPoolFunc creates a pool of n backends, each created by calling newBackend.
+// Backends that share immutable state, such as a NativeModel, may return the
+// same value from every call.
+func NewPoolFunc(size int, newBackend func() (Backend, error)) (*Pool, error) {
 	if size <= 0 {
 		size = 1
 	}
 
 	pool := &Pool{
-		sessions:  make(chan *Session, size),
-		modelPath: modelPath,
-		size:      size,
+		sessions: make(chan Backend, size),
+		size:     size,
 	}
 
 	// Pre-create all sessions
 	for i := 0; i < size; i++ {
-		session, err := NewSession(modelPath)
+		session, err := newBackend()
 		if err != nil {
 			// Clean up already created sessions
 			_ = pool.Close() // Best-effort cleanup; original error takes precedence
 			return nil, fmt.Errorf("creating session %d: %w", i, err)
 		}
+		if i == 0 {
+			pool.maxSeqLen = session.MaxSequenceLength()
+			pool.numLabels = NumLabels(session)
+		}
 		pool.sessions <- session
 	}
 
@@ -44,7 +86,7 @@ func NewPool(modelPath string, size int) (*Pool, error) {
 
 // Acquire gets a session from the pool, blocking if none available.
 // Respects context cancellation. Returns error if pool is closed.
-func (p *Pool) Acquire(ctx context.Context) (*Session, error) {
+func (p *Pool) Acquire(ctx context.Context) (Backend, error) {
 	select {
 	case session, ok := <-p.sessions:
 		if !ok {
@@ -57,7 +99,7 @@ func (p *Pool) Acquire(ctx context.Context) (*Session, error) {
 }
 
 // Release returns a session to the pool.
-func (p *Pool) Release(s *Session) {
+func (p *Pool) Release(s Backend) {
 	if s == nil {
 		return
 	}
@@ -99,6 +141,17 @@ func (p *Pool) Close() error {
 	return errors.Join(errs...)
 }
 
+// MaxSequenceLength returns the longest sequence the pooled backends accept.
+func (p *Pool) MaxSequenceLength() int {
+	return p.maxSeqLen
+}
+
+// NumLabels returns the number of labels the pooled backends predict per
+// token.
+func (p *Pool) NumLabels() int {
+	return p.numLabels
+}
+
 // Size returns the pool size.
 func (p *Pool) Size() int {
 	return p.size
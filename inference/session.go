@@ -39,17 +39,60 @@ func initORT() error {
 // Session wraps an ONNX Runtime session for SaT inference.
 type Session struct {
 	session *ort.DynamicAdvancedSession
+	sig     signature
 	mu      sync.Mutex
 	closed  bool
 }
 
 // NewSession creates a new ONNX session from a model file.
 func NewSession(modelPath string) (*Session, error) {
+	return NewSessionWithConfig(modelPath, SessionConfig{})
+}
+
+// NewSessionWithConfig creates a new ONNX session from a model file using the
+// given session configuration.
+func NewSessionWithConfig(modelPath string, cfg SessionConfig) (*Session, error) {
 	// Check file exists
 	if _, err := os.Stat(modelPath); err != nil {
 		return nil, fmt.Errorf("model file: %w", err)
 	}
 
+	return newSession(cfg, modelPath, nil)
+}
+
+// NewSessionFromBytes creates a new ONNX session from the contents of a model
+// file, such as one embedded with go:embed. ONNX Runtime copies the data, so
+// the caller may reuse it once the session is created.
+func NewSessionFromBytes(data []byte) (*Session, error) {
+	return NewSessionFromBytesWithConfig(data, SessionConfig{})
+}
+
+// NewSessionFromBytesWithConfig creates a new ONNX session from the contents
+// of a model file using the given session configuration.
+func NewSessionFromBytesWithConfig(data []byte, cfg SessionConfig) (*Session, error) {
+	if len(data) == 0 {
+		return nil, errors.New("model data is empty")
+	}
+
+	return newSession(cfg, "", data)
+}
+
+// newSession initializes ONNX Runtime and creates a session for the model in
+// data, or at modelPath if data is nil. The preoptimized model named by cfg
+// replaces either if it exists. The model's inputs and outputs are inspected
+// so that tensors match its signature; unsupported models fail with
+// ErrUnsupportedModel.
+func newSession(cfg SessionConfig, modelPath string, data []byte) (*Session, error) {
+	preoptimized, err := cfg.preoptimized()
+	if err != nil {
+		return nil, err
+	}
+	if preoptimized {
+		// The model is already optimized; don't pay for it again
+		cfg.GraphOptimization = GraphOptimizationDisabled
+		modelPath, data = cfg.PreoptimizedModelPath, nil
+	}
+
 	if err := initORT(); err != nil {
 		return nil, fmt.Errorf("initializing ONNX runtime: %w", err)
 	}
@@ -61,25 +104,121 @@ func NewSession(modelPath string) (*Session, error) {
 	}
 	defer func() { _ = options.Destroy() }() // Cleanup error doesn't affect success
 
-	// Define input/output names (from model inspection)
-	inputNames := []string{"input_ids", "attention_mask"}
-	outputNames := []string{"logits"}
+	if err := cfg.apply(options); err != nil {
+		return nil, fmt.Errorf("configuring session: %w", err)
+	}
+
+	var inputs, outputs []ort.InputOutputInfo
+	if data != nil {
+		inputs, outputs, err = ort.GetInputOutputInfoWithONNXData(data)
+	} else {
+		inputs, outputs, err = ort.GetInputOutputInfoWithOptions(modelPath, options)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("inspecting model: %w", err)
+	}
+	sig, err := resolveSignature(inputs, outputs)
+	if err != nil {
+		return nil, err
+	}
 
-	session, err := ort.NewDynamicAdvancedSession(
-		modelPath,
-		inputNames,
-		outputNames,
-		options,
-	)
+	inputNames := []string{sig.inputIDs.name, sig.mask.name}
+	outputNames := []string{sig.logits.name}
+
+	var session *ort.DynamicAdvancedSession
+	if data != nil {
+		session, err = ort.NewDynamicAdvancedSessionWithONNXData(data, inputNames, outputNames, options)
+	} else {
+		session, err = ort.NewDynamicAdvancedSession(modelPath, inputNames, outputNames, options)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("creating session: %w", err)
 	}
 
-	return &Session{session: session}, nil
+	return &Session{session: session, sig: sig}, nil
 }
 
-// Infer runs the model on tokenized input, returns per-token logits.
+// Infer runs the model on tokenized input, returns per-token logits. For
+// models with several labels, the logits of the first label are returned.
 func (s *Session) Infer(ctx context.Context, inputIDs, attentionMask []int64) ([]float32, error) {
+	logits, err := s.run(ctx, inputIDs, attentionMask, 1, int64(len(inputIDs)))
+	if err != nil {
+		return nil, err
+	}
+	return labelColumn(logits, int(s.sig.numLabels), 0), nil
+}
+
+// InferBatch runs the model on several token sequences in a single call and
+// returns per-token logits for each. Sequences shorter than the longest are
+// right-padded with padID and masked out, and their logits are trimmed back
+// to the original length. For models with several labels, the logits of the
+// first label are returned.
+func (s *Session) InferBatch(ctx context.Context, batch [][]int64, padID int64) ([][]float32, error) {
+	results, err := s.InferBatchLabels(ctx, batch, padID)
+	if err != nil {
+		return nil, err
+	}
+	for row := range results {
+		results[row] = labelColumn(results[row], int(s.sig.numLabels), 0)
+	}
+	return results, nil
+}
+
+// InferBatchLabels is like InferBatch but returns the logits of every label,
+// NumLabels values per token in row-major order.
+func (s *Session) InferBatchLabels(ctx context.Context, batch [][]int64, padID int64) ([][]float32, error) {
+	if len(batch) == 0 {
+		return nil, nil
+	}
+
+	seqLen := 0
+	for _, ids := range batch {
+		seqLen = max(seqLen, len(ids))
+	}
+
+	// Pad every row to seqLen, masking out the padding
+	inputIDs := make([]int64, len(batch)*seqLen)
+	attentionMask := make([]int64, len(batch)*seqLen)
+	for row, ids := range batch {
+		offset := row * seqLen
+		for i := 0; i < seqLen; i++ {
+			if i < len(ids) {
+				inputIDs[offset+i] = ids[i]
+				attentionMask[offset+i] = 1
+			} else {
+				inputIDs[offset+i] = padID
+			}
+		}
+	}
+
+	logits, err := s.run(ctx, inputIDs, attentionMask, int64(len(batch)), int64(seqLen))
+	if err != nil {
+		return nil, err
+	}
+
+	labels := int(s.sig.numLabels)
+	results := make([][]float32, len(batch))
+	for row, ids := range batch {
+		offset := row * seqLen * labels
+		end := offset + len(ids)*labels
+		results[row] = logits[offset:end:end]
+	}
+	return results, nil
+}
+
+// NumLabels returns the number of labels the model predicts per token.
+func (s *Session) NumLabels() int {
+	return int(s.sig.numLabels)
+}
+
+// MaxSequenceLength returns the longest sequence the session accepts.
+func (s *Session) MaxSequenceLength() int {
+	return DefaultMaxSequenceLength
+}
+
+// run executes the model on row-major inputs of shape [batchSize, seqLen] and
+// returns the row-major logits.
+func (s *Session) run(ctx context.Context, inputIDs, attentionMask []int64, batchSize, seqLen int64) ([]float32, error) {
 	// Check context before expensive operation
 	select {
 	case <-ctx.Done():
@@ -94,57 +233,30 @@ func (s *Session) Infer(ctx context.Context, inputIDs, attentionMask []int64) ([
 		return nil, ErrSessionClosed
 	}
 
-	batchSize := int64(1)
-	seqLen := int64(len(inputIDs))
-
-	// Create input tensors
-	inputIDsTensor, err := ort.NewTensor(
-		ort.NewShape(batchSize, seqLen),
-		inputIDs,
-	)
+	// Create input tensors in the types the model declares
+	inputIDsTensor, err := s.sig.newIDsTensor(inputIDs, batchSize, seqLen)
 	if err != nil {
-		return nil, fmt.Errorf("creating input_ids tensor: %w", err)
+		return nil, fmt.Errorf("creating %s tensor: %w", s.sig.inputIDs.name, err)
 	}
 	defer func() { _ = inputIDsTensor.Destroy() }()
 
-	// Convert attention_mask to float16 bytes
-	// Model expects attention_mask as float16, not int64
-	attentionMaskF16 := make([]byte, len(attentionMask)*2)
-	for i, v := range attentionMask {
-		// float16: 0.0 = 0x0000, 1.0 = 0x3C00 (little-endian: 0x00, 0x3C)
-		if v != 0 {
-			attentionMaskF16[i*2] = 0x00
-			attentionMaskF16[i*2+1] = 0x3C
-		}
-		// else: already zero
-	}
-	attentionMaskTensor, err := ort.NewCustomDataTensor(
-		ort.NewShape(batchSize, seqLen),
-		attentionMaskF16,
-		ort.TensorElementDataTypeFloat16,
-	)
+	attentionMaskTensor, err := s.sig.newMaskTensor(attentionMask, batchSize, seqLen)
 	if err != nil {
-		return nil, fmt.Errorf("creating attention_mask tensor: %w", err)
+		return nil, fmt.Errorf("creating %s tensor: %w", s.sig.mask.name, err)
 	}
 	defer func() { _ = attentionMaskTensor.Destroy() }()
 
 	// Prepare inputs as Value slice
 	inputs := []ort.Value{inputIDsTensor, attentionMaskTensor}
 
-	// Pre-allocate output tensor as float16 with shape [1, seqLen, 1]
-	// The model outputs float16 logits
-	outputData := make([]byte, seqLen*2) // seqLen * 1 * 2 bytes per float16
-	outputTensor, err := ort.NewCustomDataTensor(
-		ort.NewShape(batchSize, seqLen, 1),
-		outputData,
-		ort.TensorElementDataTypeFloat16,
-	)
+	// Pre-allocate the output tensor with the model's logit shape and type
+	output, err := s.sig.newLogitsOutput(batchSize, seqLen)
 	if err != nil {
 		return nil, fmt.Errorf("creating output tensor: %w", err)
 	}
-	defer func() { _ = outputTensor.Destroy() }()
+	defer func() { _ = output.value.Destroy() }()
 
-	outputs := []ort.Value{outputTensor}
+	outputs := []ort.Value{output.value}
 
 	// Run inference
 	err = s.session.Run(inputs, outputs)
@@ -152,17 +264,7 @@ func (s *Session) Infer(ctx context.Context, inputIDs, attentionMask []int64) ([
 		return nil, fmt.Errorf("running inference: %w", err)
 	}
 
-	// Convert float16 bytes to float32 logits
-	logits := make([]float32, seqLen)
-	for i := int64(0); i < seqLen; i++ {
-		// Read float16 (2 bytes, little-endian)
-		low := uint16(outputData[i*2])
-		high := uint16(outputData[i*2+1])
-		f16bits := low | (high << 8)
-		logits[i] = float16ToFloat32(f16bits)
-	}
-
-	return logits, nil
+	return output.float32s(), nil
 }
 
 // float16ToFloat32 converts a 16-bit float to 32-bit float.
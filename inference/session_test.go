@@ -42,6 +42,38 @@ func TestNewSession(t *testing.T) {
 	}
 }
 
+func TestNewSessionFromBytes_Empty(t *testing.T) {
+	if _, err := NewSessionFromBytes(nil); err == nil {
+		t.Error("expected error for empty model data")
+	}
+}
+
+func TestNewSessionFromBytes(t *testing.T) {
+	modelPath := "../testdata/model_optimized.onnx"
+
+	data, err := os.ReadFile(modelPath)
+	if err != nil {
+		t.Skipf("Skipping: model not available at %s", modelPath)
+	}
+
+	session, err := NewSessionFromBytes(data)
+	if err != nil {
+		if isORTUnavailableError(err) {
+			t.Skipf("Skipping: ONNX runtime not available: %v", err)
+		}
+		t.Fatalf("NewSessionFromBytes failed: %v", err)
+	}
+	defer func() { _ = session.Close() }()
+
+	logits, err := session.Infer(context.Background(), []int64{0, 35378, 8999, 5, 2}, []int64{1, 1, 1, 1, 1})
+	if err != nil {
+		t.Fatalf("Infer failed: %v", err)
+	}
+	if len(logits) != 5 {
+		t.Errorf("expected 5 logits, got %d", len(logits))
+	}
+}
+
 func TestSession_Infer(t *testing.T) {
 	modelPath := "../testdata/model_optimized.onnx"
 
@@ -79,6 +111,58 @@ func TestSession_Infer(t *testing.T) {
 	}
 }
 
+func TestSession_InferBatch(t *testing.T) {
+	modelPath := "../testdata/model_optimized.onnx"
+
+	// Skip if model file doesn't exist
+	if _, err := os.Stat(modelPath); err != nil {
+		t.Skipf("Skipping: model not available at %s", modelPath)
+	}
+
+	session, err := NewSession(modelPath)
+	if err != nil {
+		if isORTUnavailableError(err) {
+			t.Skipf("Skipping: ONNX runtime not available: %v", err)
+		}
+		t.Fatalf("NewSession failed: %v", err)
+	}
+	defer func() { _ = session.Close() }()
+
+	batch := [][]int64{
+		{0, 35378, 8, 38, 3714, 43033, 5, 2}, // <s> Hello , I like cats . </s>
+		{0, 35378, 5, 2},                     // <s> Hello . </s>
+	}
+
+	ctx := context.Background()
+	results, err := session.InferBatch(ctx, batch, 1)
+	if err != nil {
+		t.Fatalf("InferBatch failed: %v", err)
+	}
+	if len(results) != len(batch) {
+		t.Fatalf("expected %d results, got %d", len(batch), len(results))
+	}
+
+	// Padded rows must match running each sequence on its own
+	for i, ids := range batch {
+		mask := make([]int64, len(ids))
+		for j := range mask {
+			mask[j] = 1
+		}
+		want, err := session.Infer(ctx, ids, mask)
+		if err != nil {
+			t.Fatalf("Infer failed: %v", err)
+		}
+		if len(results[i]) != len(want) {
+			t.Fatalf("row %d: expected %d logits, got %d", i, len(want), len(results[i]))
+		}
+		for j := range want {
+			if diff := results[i][j] - want[j]; diff > 0.05 || diff < -0.05 {
+				t.Errorf("row %d token %d: batched logit %f, single %f", i, j, results[i][j], want[j])
+			}
+		}
+	}
+}
+
 func TestSession_Infer_ContextCancellation(t *testing.T) {
 	modelPath := "../testdata/model_optimized.onnx"
 
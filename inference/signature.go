@@ -0,0 +1,211 @@
+package inference
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// ErrUnsupportedModel indicates the model's inputs or outputs do not match
+// any signature the Session knows how to drive.
+var ErrUnsupportedModel = errors.New("inference: unsupported model signature")
+
+// Tensor names used by SaT exports.
+const (
+	inputIDsName      = "input_ids"
+	attentionMaskName = "attention_mask"
+	logitsName        = "logits"
+)
+
+// tensorSpec names a model input or output and its element type.
+type tensorSpec struct {
+	name  string
+	dtype ort.TensorElementDataType
+}
+
+// signature describes how to feed a model and read its output, as discovered
+// from the model file.
+type signature struct {
+	inputIDs  tensorSpec
+	mask      tensorSpec
+	logits    tensorSpec
+	logitRank int   // 2 for [batch, seq], 3 for [batch, seq, labels]
+	numLabels int64 // size of the label dimension; 1 when logitRank is 2
+}
+
+// resolveSignature matches the inputs and outputs reported by ONNX Runtime
+// against the shapes SaT exports use: int64 or int32 token IDs, an
+// attention mask of int64, int32, float16 or float32, and float16 or float32
+// logits of shape [batch, seq] or [batch, seq, labels].
+func resolveSignature(inputs, outputs []ort.InputOutputInfo) (signature, error) {
+	var sig signature
+
+	for _, in := range inputs {
+		switch in.Name {
+		case inputIDsName:
+			switch in.DataType {
+			case ort.TensorElementDataTypeInt64, ort.TensorElementDataTypeInt32:
+			default:
+				return signature{}, fmt.Errorf("%w: input %s has type %v, want int64 or int32", ErrUnsupportedModel, in.Name, in.DataType)
+			}
+			sig.inputIDs = tensorSpec{name: in.Name, dtype: in.DataType}
+		case attentionMaskName:
+			switch in.DataType {
+			case ort.TensorElementDataTypeInt64, ort.TensorElementDataTypeInt32,
+				ort.TensorElementDataTypeFloat16, ort.TensorElementDataTypeFloat:
+			default:
+				return signature{}, fmt.Errorf("%w: input %s has type %v, want int64, int32, float16 or float32", ErrUnsupportedModel, in.Name, in.DataType)
+			}
+			sig.mask = tensorSpec{name: in.Name, dtype: in.DataType}
+		default:
+			return signature{}, fmt.Errorf("%w: unexpected input %s; want %s and %s", ErrUnsupportedModel, in.Name, inputIDsName, attentionMaskName)
+		}
+	}
+	if sig.inputIDs.name == "" || sig.mask.name == "" {
+		return signature{}, fmt.Errorf("%w: model inputs %v; want %s and %s", ErrUnsupportedModel, ioNames(inputs), inputIDsName, attentionMaskName)
+	}
+
+	// Prefer the conventional name; single-output exports may call it anything
+	var out *ort.InputOutputInfo
+	for i := range outputs {
+		if outputs[i].Name == logitsName {
+			out = &outputs[i]
+		}
+	}
+	if out == nil && len(outputs) == 1 {
+		out = &outputs[0]
+	}
+	if out == nil {
+		return signature{}, fmt.Errorf("%w: model outputs %v; want %s", ErrUnsupportedModel, ioNames(outputs), logitsName)
+	}
+
+	switch out.DataType {
+	case ort.TensorElementDataTypeFloat16, ort.TensorElementDataTypeFloat:
+	default:
+		return signature{}, fmt.Errorf("%w: output %s has type %v, want float16 or float32", ErrUnsupportedModel, out.Name, out.DataType)
+	}
+	sig.logits = tensorSpec{name: out.Name, dtype: out.DataType}
+
+	switch len(out.Dimensions) {
+	case 2:
+		sig.logitRank, sig.numLabels = 2, 1
+	case 3:
+		if out.Dimensions[2] <= 0 {
+			return signature{}, fmt.Errorf("%w: output %s has dynamic label dimension in shape %v", ErrUnsupportedModel, out.Name, out.Dimensions)
+		}
+		sig.logitRank, sig.numLabels = 3, out.Dimensions[2]
+	default:
+		return signature{}, fmt.Errorf("%w: output %s has shape %v, want [batch, seq] or [batch, seq, labels]", ErrUnsupportedModel, out.Name, out.Dimensions)
+	}
+
+	return sig, nil
+}
+
+// ioNames returns the names of the given inputs or outputs.
+func ioNames(infos []ort.InputOutputInfo) []string {
+	names := make([]string, len(infos))
+	for i, info := range infos {
+		names[i] = info.Name
+	}
+	return names
+}
+
+// newIDsTensor creates the token ID input of shape [batchSize, seqLen].
+func (sig signature) newIDsTensor(ids []int64, batchSize, seqLen int64) (ort.Value, error) {
+	shape := ort.NewShape(batchSize, seqLen)
+	if sig.inputIDs.dtype == ort.TensorElementDataTypeInt32 {
+		ids32 := make([]int32, len(ids))
+		for i, id := range ids {
+			ids32[i] = int32(id)
+		}
+		return ort.NewTensor(shape, ids32)
+	}
+	return ort.NewTensor(shape, ids)
+}
+
+// newMaskTensor creates the attention mask input of shape
+// [batchSize, seqLen], where non-zero entries of mask mark real tokens.
+func (sig signature) newMaskTensor(mask []int64, batchSize, seqLen int64) (ort.Value, error) {
+	shape := ort.NewShape(batchSize, seqLen)
+	switch sig.mask.dtype {
+	case ort.TensorElementDataTypeInt32:
+		mask32 := make([]int32, len(mask))
+		for i, v := range mask {
+			if v != 0 {
+				mask32[i] = 1
+			}
+		}
+		return ort.NewTensor(shape, mask32)
+	case ort.TensorElementDataTypeFloat:
+		maskF32 := make([]float32, len(mask))
+		for i, v := range mask {
+			if v != 0 {
+				maskF32[i] = 1
+			}
+		}
+		return ort.NewTensor(shape, maskF32)
+	case ort.TensorElementDataTypeFloat16:
+		maskF16 := make([]byte, len(mask)*2)
+		for i, v := range mask {
+			// float16: 0.0 = 0x0000, 1.0 = 0x3C00 (little-endian: 0x00, 0x3C)
+			if v != 0 {
+				maskF16[i*2+1] = 0x3C
+			}
+		}
+		return ort.NewCustomDataTensor(shape, maskF16, ort.TensorElementDataTypeFloat16)
+	}
+	return ort.NewTensor(shape, mask)
+}
+
+// logitsOutput is a pre-allocated output tensor for the model's logits.
+type logitsOutput struct {
+	value ort.Value
+	f32   []float32 // backing data for float32 outputs
+	f16   []byte    // backing data for float16 outputs
+}
+
+// newLogitsOutput allocates the logits output for a batch.
+func (sig signature) newLogitsOutput(batchSize, seqLen int64) (logitsOutput, error) {
+	shape := ort.NewShape(batchSize, seqLen)
+	if sig.logitRank == 3 {
+		shape = ort.NewShape(batchSize, seqLen, sig.numLabels)
+	}
+
+	var out logitsOutput
+	var err error
+	if sig.logits.dtype == ort.TensorElementDataTypeFloat {
+		out.f32 = make([]float32, shape.FlattenedSize())
+		out.value, err = ort.NewTensor(shape, out.f32)
+	} else {
+		out.f16 = make([]byte, shape.FlattenedSize()*2)
+		out.value, err = ort.NewCustomDataTensor(shape, out.f16, ort.TensorElementDataTypeFloat16)
+	}
+	return out, err
+}
+
+// float32s returns the output as row-major float32 values.
+func (o logitsOutput) float32s() []float32 {
+	if o.f32 != nil {
+		return o.f32
+	}
+	logits := make([]float32, len(o.f16)/2)
+	for i := range logits {
+		logits[i] = float16ToFloat32(binary.LittleEndian.Uint16(o.f16[i*2:]))
+	}
+	return logits
+}
+
+// labelColumn returns column label of row-major logits with numLabels values
+// per token.
+func labelColumn(logits []float32, numLabels, label int) []float32 {
+	if numLabels == 1 {
+		return logits
+	}
+	column := make([]float32, len(logits)/numLabels)
+	for i := range column {
+		column[i] = logits[i*numLabels+label]
+	}
+	return column
+}
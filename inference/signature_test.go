@@ -0,0 +1,147 @@
+package inference
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+func TestResolveSignature(t *testing.T) {
+	ids := ort.InputOutputInfo{Name: "input_ids", DataType: ort.TensorElementDataTypeInt64, Dimensions: ort.NewShape(-1, -1)}
+	mask := func(dtype ort.TensorElementDataType) ort.InputOutputInfo {
+		return ort.InputOutputInfo{Name: "attention_mask", DataType: dtype, Dimensions: ort.NewShape(-1, -1)}
+	}
+	output := func(name string, dtype ort.TensorElementDataType, dims ...int64) ort.InputOutputInfo {
+		return ort.InputOutputInfo{Name: name, DataType: dtype, Dimensions: ort.NewShape(dims...)}
+	}
+
+	tests := []struct {
+		name      string
+		inputs    []ort.InputOutputInfo
+		outputs   []ort.InputOutputInfo
+		wantMask  ort.TensorElementDataType
+		wantOut   string
+		wantRank  int
+		wantLabel int64
+		wantErr   bool
+	}{
+		{
+			name:      "fp16 export",
+			inputs:    []ort.InputOutputInfo{ids, mask(ort.TensorElementDataTypeFloat16)},
+			outputs:   []ort.InputOutputInfo{output("logits", ort.TensorElementDataTypeFloat16, -1, -1, 1)},
+			wantMask:  ort.TensorElementDataTypeFloat16,
+			wantOut:   "logits",
+			wantRank:  3,
+			wantLabel: 1,
+		},
+		{
+			name:      "fp32 export with int64 mask",
+			inputs:    []ort.InputOutputInfo{mask(ort.TensorElementDataTypeInt64), ids},
+			outputs:   []ort.InputOutputInfo{output("logits", ort.TensorElementDataTypeFloat, -1, -1, 1)},
+			wantMask:  ort.TensorElementDataTypeInt64,
+			wantOut:   "logits",
+			wantRank:  3,
+			wantLabel: 1,
+		},
+		{
+			name:      "multi-label",
+			inputs:    []ort.InputOutputInfo{ids, mask(ort.TensorElementDataTypeFloat)},
+			outputs:   []ort.InputOutputInfo{output("hidden", ort.TensorElementDataTypeFloat, -1, -1, 8), output("logits", ort.TensorElementDataTypeFloat, -1, -1, 4)},
+			wantMask:  ort.TensorElementDataTypeFloat,
+			wantOut:   "logits",
+			wantRank:  3,
+			wantLabel: 4,
+		},
+		{
+			name:      "single unnamed rank-2 output",
+			inputs:    []ort.InputOutputInfo{ids, mask(ort.TensorElementDataTypeInt32)},
+			outputs:   []ort.InputOutputInfo{output("output_0", ort.TensorElementDataTypeFloat, -1, -1)},
+			wantMask:  ort.TensorElementDataTypeInt32,
+			wantOut:   "output_0",
+			wantRank:  2,
+			wantLabel: 1,
+		},
+		{
+			name:    "missing mask",
+			inputs:  []ort.InputOutputInfo{ids},
+			outputs: []ort.InputOutputInfo{output("logits", ort.TensorElementDataTypeFloat, -1, -1, 1)},
+			wantErr: true,
+		},
+		{
+			name:    "extra input",
+			inputs:  []ort.InputOutputInfo{ids, mask(ort.TensorElementDataTypeInt64), {Name: "token_type_ids", DataType: ort.TensorElementDataTypeInt64}},
+			outputs: []ort.InputOutputInfo{output("logits", ort.TensorElementDataTypeFloat, -1, -1, 1)},
+			wantErr: true,
+		},
+		{
+			name:    "float ids",
+			inputs:  []ort.InputOutputInfo{{Name: "input_ids", DataType: ort.TensorElementDataTypeFloat}, mask(ort.TensorElementDataTypeInt64)},
+			outputs: []ort.InputOutputInfo{output("logits", ort.TensorElementDataTypeFloat, -1, -1, 1)},
+			wantErr: true,
+		},
+		{
+			name:    "integer logits",
+			inputs:  []ort.InputOutputInfo{ids, mask(ort.TensorElementDataTypeInt64)},
+			outputs: []ort.InputOutputInfo{output("logits", ort.TensorElementDataTypeInt64, -1, -1, 1)},
+			wantErr: true,
+		},
+		{
+			name:    "ambiguous outputs",
+			inputs:  []ort.InputOutputInfo{ids, mask(ort.TensorElementDataTypeInt64)},
+			outputs: []ort.InputOutputInfo{output("a", ort.TensorElementDataTypeFloat, -1, -1, 1), output("b", ort.TensorElementDataTypeFloat, -1, -1, 1)},
+			wantErr: true,
+		},
+		{
+			name:    "dynamic label dimension",
+			inputs:  []ort.InputOutputInfo{ids, mask(ort.TensorElementDataTypeInt64)},
+			outputs: []ort.InputOutputInfo{output("logits", ort.TensorElementDataTypeFloat, -1, -1, -1)},
+			wantErr: true,
+		},
+		{
+			name:    "rank 4 output",
+			inputs:  []ort.InputOutputInfo{ids, mask(ort.TensorElementDataTypeInt64)},
+			outputs: []ort.InputOutputInfo{output("logits", ort.TensorElementDataTypeFloat, -1, -1, 1, 1)},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sig, err := resolveSignature(tt.inputs, tt.outputs)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnsupportedModel) {
+					t.Errorf("expected ErrUnsupportedModel, got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveSignature failed: %v", err)
+			}
+			if sig.mask.dtype != tt.wantMask {
+				t.Errorf("mask type = %v, want %v", sig.mask.dtype, tt.wantMask)
+			}
+			if sig.logits.name != tt.wantOut {
+				t.Errorf("output = %q, want %q", sig.logits.name, tt.wantOut)
+			}
+			if sig.logitRank != tt.wantRank || sig.numLabels != tt.wantLabel {
+				t.Errorf("rank, labels = %d, %d, want %d, %d", sig.logitRank, sig.numLabels, tt.wantRank, tt.wantLabel)
+			}
+		})
+	}
+}
+
+func TestLabelColumn(t *testing.T) {
+	logits := []float32{1, 2, 3, 4, 5, 6}
+
+	if got := labelColumn(logits, 1, 0); !slices.Equal(got, logits) {
+		t.Errorf("single label = %v, want %v", got, logits)
+	}
+	if got, want := labelColumn(logits, 3, 0), []float32{1, 4}; !slices.Equal(got, want) {
+		t.Errorf("label 0 of 3 = %v, want %v", got, want)
+	}
+	if got, want := labelColumn(logits, 2, 1), []float32{2, 4, 6}; !slices.Equal(got, want) {
+		t.Errorf("label 1 of 2 = %v, want %v", got, want)
+	}
+}
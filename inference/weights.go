@@ -0,0 +1,343 @@
+package inference
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ErrInvalidWeights indicates model weights could not be decoded or do not
+// match the expected architecture.
+var ErrInvalidWeights = errors.New("inference: invalid model weights")
+
+// dataType identifies the element encoding of a weight tensor.
+type dataType int
+
+const (
+	dtypeF32 dataType = iota
+	dtypeF16
+	dtypeBF16
+)
+
+// size returns the number of bytes per element.
+func (d dataType) size() int {
+	if d == dtypeF32 {
+		return 4
+	}
+	return 2
+}
+
+// tensorData is a weight tensor decoded from a model file. Elements are kept
+// in their stored little-endian encoding and converted to float32 on demand,
+// so large tables such as word embeddings need not be expanded in memory.
+type tensorData struct {
+	shape []int
+	dtype dataType
+	raw   []byte
+}
+
+// numElements returns the product of the tensor's dimensions.
+func (t tensorData) numElements() int {
+	n := 1
+	for _, d := range t.shape {
+		n *= d
+	}
+	return n
+}
+
+// float32s decodes the whole tensor.
+func (t tensorData) float32s() []float32 {
+	out := make([]float32, t.numElements())
+	t.decode(0, out)
+	return out
+}
+
+// decode converts len(dst) elements starting at element index from into dst.
+func (t tensorData) decode(from int, dst []float32) {
+	size := t.dtype.size()
+	raw := t.raw[from*size:]
+	for i := range dst {
+		switch t.dtype {
+		case dtypeF32:
+			dst[i] = math.Float32frombits(binary.LittleEndian.Uint32(raw[i*4:]))
+		case dtypeF16:
+			dst[i] = float16ToFloat32(binary.LittleEndian.Uint16(raw[i*2:]))
+		case dtypeBF16:
+			dst[i] = math.Float32frombits(uint32(binary.LittleEndian.Uint16(raw[i*2:])) << 16)
+		}
+	}
+}
+
+// isSafetensors reports whether data looks like a safetensors file: an
+// 8-byte header length that fits in the file, followed by a JSON object.
+func isSafetensors(data []byte) bool {
+	if len(data) < 9 || data[8] != '{' {
+		return false
+	}
+	return binary.LittleEndian.Uint64(data) <= uint64(len(data)-8)
+}
+
+// loadSafetensors decodes the tensors of a safetensors file held in data.
+// The returned tensors reference data rather than copying it.
+func loadSafetensors(data []byte) (map[string]tensorData, error) {
+	if len(data) < 8 {
+		return nil, fmt.Errorf("%w: safetensors file too short", ErrInvalidWeights)
+	}
+	headerLen := binary.LittleEndian.Uint64(data)
+	if headerLen > uint64(len(data)-8) {
+		return nil, fmt.Errorf("%w: safetensors header length %d exceeds file size", ErrInvalidWeights, headerLen)
+	}
+
+	var header map[string]json.RawMessage
+	if err := json.Unmarshal(data[8:8+headerLen], &header); err != nil {
+		return nil, fmt.Errorf("%w: parsing safetensors header: %w", ErrInvalidWeights, err)
+	}
+	body := data[8+headerLen:]
+
+	tensors := make(map[string]tensorData, len(header))
+	for name, rawInfo := range header {
+		if name == "__metadata__" {
+			continue
+		}
+
+		var info struct {
+			DType       string `json:"dtype"`
+			Shape       []int  `json:"shape"`
+			DataOffsets [2]int `json:"data_offsets"`
+		}
+		if err := json.Unmarshal(rawInfo, &info); err != nil {
+			return nil, fmt.Errorf("%w: tensor %s: %w", ErrInvalidWeights, name, err)
+		}
+
+		var dtype dataType
+		switch info.DType {
+		case "F32":
+			dtype = dtypeF32
+		case "F16":
+			dtype = dtypeF16
+		case "BF16":
+			dtype = dtypeBF16
+		default:
+			// Non-float tensors (e.g. position_ids buffers) are not weights
+			continue
+		}
+
+		t := tensorData{shape: info.Shape, dtype: dtype}
+		start, end := info.DataOffsets[0], info.DataOffsets[1]
+		if start < 0 || end < start || end > len(body) || end-start != t.numElements()*dtype.size() {
+			return nil, fmt.Errorf("%w: tensor %s has invalid data offsets %v", ErrInvalidWeights, name, info.DataOffsets)
+		}
+		t.raw = body[start:end:end]
+		tensors[name] = t
+	}
+
+	return tensors, nil
+}
+
+// ONNX protobuf field numbers used when reading initializers.
+const (
+	onnxModelGraph       = 7  // ModelProto.graph
+	onnxGraphInitializer = 5  // GraphProto.initializer
+	onnxTensorDims       = 1  // TensorProto.dims
+	onnxTensorDataType   = 2  // TensorProto.data_type
+	onnxTensorFloatData  = 4  // TensorProto.float_data
+	onnxTensorInt32Data  = 5  // TensorProto.int32_data
+	onnxTensorName       = 8  // TensorProto.name
+	onnxTensorRawData    = 9  // TensorProto.raw_data
+	onnxTensorDataLoc    = 14 // TensorProto.data_location
+	onnxTypeFloat        = 1
+	onnxTypeFloat16      = 10
+	onnxTypeBFloat16     = 16
+	onnxDataLocExternal  = 1
+)
+
+// loadONNXInitializers decodes the floating-point initializers of an ONNX
+// model held in data. Only the fields needed to recover weights are parsed.
+func loadONNXInitializers(data []byte) (map[string]tensorData, error) {
+	graph, err := protoField(data, onnxModelGraph)
+	if err != nil {
+		return nil, err
+	}
+	if graph == nil {
+		return nil, fmt.Errorf("%w: ONNX model has no graph", ErrInvalidWeights)
+	}
+
+	tensors := make(map[string]tensorData)
+	err = protoEach(graph, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		if num != onnxGraphInitializer || typ != protowire.BytesType {
+			return nil
+		}
+		name, t, ok, err := parseONNXTensor(v)
+		if err != nil {
+			return err
+		}
+		if ok {
+			tensors[name] = t
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tensors, nil
+}
+
+// parseONNXTensor decodes a TensorProto. ok is false for tensors that are not
+// floating point and therefore cannot be model weights.
+func parseONNXTensor(data []byte) (name string, t tensorData, ok bool, err error) {
+	var (
+		onnxType  uint64
+		raw       []byte
+		floats    []byte // float_data re-encoded as little-endian bytes
+		halfs     []byte // int32_data holding 16-bit floats
+		external  bool
+		dimsValid = true
+	)
+
+	err = protoEach(data, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		switch num {
+		case onnxTensorDims:
+			vals, err := protoVarints(typ, v)
+			if err != nil {
+				return err
+			}
+			for _, d := range vals {
+				if int64(d) < 0 {
+					dimsValid = false
+				}
+				t.shape = append(t.shape, int(d))
+			}
+		case onnxTensorDataType:
+			n, _ := protowire.ConsumeVarint(v)
+			onnxType = n
+		case onnxTensorFloatData:
+			floats = append(floats, protoFixed32s(typ, v)...)
+		case onnxTensorInt32Data:
+			vals, err := protoVarints(typ, v)
+			if err != nil {
+				return err
+			}
+			for _, x := range vals {
+				halfs = binary.LittleEndian.AppendUint16(halfs, uint16(x))
+			}
+		case onnxTensorName:
+			name = string(v)
+		case onnxTensorRawData:
+			raw = v
+		case onnxTensorDataLoc:
+			n, _ := protowire.ConsumeVarint(v)
+			external = n == onnxDataLocExternal
+		}
+		return nil
+	})
+	if err != nil {
+		return "", tensorData{}, false, err
+	}
+
+	switch onnxType {
+	case onnxTypeFloat:
+		t.dtype = dtypeF32
+		if raw == nil {
+			raw = floats
+		}
+	case onnxTypeFloat16, onnxTypeBFloat16:
+		t.dtype = dtypeF16
+		if onnxType == onnxTypeBFloat16 {
+			t.dtype = dtypeBF16
+		}
+		if raw == nil {
+			raw = halfs
+		}
+	default:
+		return name, tensorData{}, false, nil
+	}
+
+	if external {
+		return "", tensorData{}, false, fmt.Errorf("%w: initializer %s uses external data", ErrInvalidWeights, name)
+	}
+	if !dimsValid || len(raw) != t.numElements()*t.dtype.size() {
+		return "", tensorData{}, false, fmt.Errorf("%w: initializer %s has %d bytes for shape %v", ErrInvalidWeights, name, len(raw), t.shape)
+	}
+	t.raw = raw
+	return name, t, true, nil
+}
+
+// protoEach calls fn for every top-level field in a protobuf message. For
+// varint and fixed fields v holds the encoded value; for length-delimited
+// fields it holds the payload.
+func protoEach(data []byte, fn func(num protowire.Number, typ protowire.Type, v []byte) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return fmt.Errorf("%w: malformed protobuf: %w", ErrInvalidWeights, protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		var v []byte
+		switch typ {
+		case protowire.BytesType:
+			payload, m := protowire.ConsumeBytes(data)
+			if m < 0 {
+				return fmt.Errorf("%w: malformed protobuf: %w", ErrInvalidWeights, protowire.ParseError(m))
+			}
+			v, n = payload, m
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return fmt.Errorf("%w: malformed protobuf: %w", ErrInvalidWeights, protowire.ParseError(n))
+			}
+			v = data[:n]
+		}
+		data = data[n:]
+
+		if err := fn(num, typ, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// protoField returns the payload of the last length-delimited field num in a
+// message, or nil if it is absent.
+func protoField(data []byte, num protowire.Number) ([]byte, error) {
+	var found []byte
+	err := protoEach(data, func(n protowire.Number, typ protowire.Type, v []byte) error {
+		if n == num && typ == protowire.BytesType {
+			found = v
+		}
+		return nil
+	})
+	return found, err
+}
+
+// protoVarints decodes a repeated varint field value, packed or not.
+func protoVarints(typ protowire.Type, v []byte) ([]uint64, error) {
+	if typ == protowire.VarintType {
+		x, _ := protowire.ConsumeVarint(v)
+		return []uint64{x}, nil
+	}
+
+	var vals []uint64
+	for len(v) > 0 {
+		x, n := protowire.ConsumeVarint(v)
+		if n < 0 {
+			return nil, fmt.Errorf("%w: malformed packed varints", ErrInvalidWeights)
+		}
+		vals = append(vals, x)
+		v = v[n:]
+	}
+	return vals, nil
+}
+
+// protoFixed32s returns the little-endian bytes of a repeated fixed32 field
+// value, packed or not.
+func protoFixed32s(typ protowire.Type, v []byte) []byte {
+	if typ == protowire.Fixed32Type || typ == protowire.BytesType {
+		return v[:len(v)/4*4]
+	}
+	return nil
+}
@@ -10,13 +10,17 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"unicode/utf8"
+
+	"github.com/jamesainslie/go-sat/internal/punct"
 )
 
 // Header contains metadata parsed from transcript file header.
 type Header struct {
-	Source  string
-	Speaker string
-	Title   string
+	Source   string
+	Speaker  string
+	Title    string
+	Language string
 }
 
 // ParseHeader extracts metadata from transcript header comments.
@@ -46,6 +50,8 @@ func ParseHeader(text string) (Header, string, error) {
 			h.Speaker = strings.TrimSpace(value)
 		} else if value, ok := strings.CutPrefix(line, "Title:"); ok {
 			h.Title = strings.TrimSpace(value)
+		} else if value, ok := strings.CutPrefix(line, "Language:"); ok {
+			h.Language = strings.TrimSpace(value)
 		}
 	}
 
@@ -74,7 +80,9 @@ type Sentence struct {
 var abbreviations = regexp.MustCompile(`(?i)\b(Mr|Mrs|Ms|Dr|Prof|Sr|Jr|vs|etc|i\.e|e\.g|U\.S|U\.K)\.$`)
 
 // ParseSentences splits text into sentences at sentence-ending punctuation.
-// Handles common abbreviations to avoid false splits.
+// Handles common abbreviations to avoid false splits. Full-width terminal
+// punctuation (。！？), as used in Chinese and Japanese, ends a sentence
+// without a following space, together with any closing quotes after it.
 func ParseSentences(text string) []Sentence {
 	if text == "" {
 		return nil
@@ -84,8 +92,21 @@ func ParseSentences(text string) []Sentence {
 	start := 0
 
 	for i := 0; i < len(text); i++ {
-		ch := text[i]
-		if ch == '.' || ch == '?' || ch == '!' {
+		end := i + 1
+		if r, size := utf8.DecodeRuneInString(text[i:]); punct.IsFullWidthTerminal(r) {
+			end = i + size
+			for end < len(text) {
+				next, size := utf8.DecodeRuneInString(text[end:])
+				if !punct.IsFullWidthTerminal(next) && !isFullWidthClosing(next) {
+					break
+				}
+				end += size
+			}
+		} else {
+			ch := text[i]
+			if ch != '.' && ch != '?' && ch != '!' {
+				continue
+			}
 			// Check if this is end of text or followed by space/newline
 			isEnd := i == len(text)-1 || text[i+1] == ' ' || text[i+1] == '\n'
 			if !isEnd {
@@ -97,20 +118,20 @@ func ParseSentences(text string) []Sentence {
 			if ch == '.' && abbreviations.MatchString(candidate) {
 				continue
 			}
+		}
 
-			end := i + 1
-			sentences = append(sentences, Sentence{
-				Text:  strings.TrimSpace(text[start:end]),
-				Start: start,
-				End:   end,
-			})
+		sentences = append(sentences, Sentence{
+			Text:  strings.TrimSpace(text[start:end]),
+			Start: start,
+			End:   end,
+		})
 
-			// Skip whitespace to find next sentence start
-			for i+1 < len(text) && (text[i+1] == ' ' || text[i+1] == '\n') {
-				i++
-			}
-			start = i + 1
+		// Skip whitespace to find next sentence start
+		i = end - 1
+		for i+1 < len(text) && (text[i+1] == ' ' || text[i+1] == '\n') {
+			i++
 		}
+		start = i + 1
 	}
 
 	// Handle remaining text without terminal punctuation
@@ -128,13 +149,24 @@ func ParseSentences(text string) []Sentence {
 	return sentences
 }
 
+// isFullWidthClosing reports whether r is a closing quote or bracket that
+// belongs to the sentence before it in Chinese or Japanese text.
+func isFullWidthClosing(r rune) bool {
+	switch r {
+	case '」', '』', '）', '】', '〕', '〉', '》', '”', '’':
+		return true
+	}
+	return false
+}
+
 // Talk represents a loaded transcript with parsed sentences.
 type Talk struct {
-	ID        string     // filename without extension
-	Source    string     // TED URL
+	ID        string // filename without extension
+	Source    string // TED URL
 	Speaker   string
 	Title     string
-	RawText   string     // body text
+	Language  string // ISO 639-1 code, or "" if unknown
+	RawText   string // body text
 	Sentences []Sentence
 }
 
@@ -158,6 +190,7 @@ func LoadTalk(path string) (*Talk, error) {
 		Source:    header.Source,
 		Speaker:   header.Speaker,
 		Title:     header.Title,
+		Language:  header.Language,
 		RawText:   body,
 		Sentences: ParseSentences(body),
 	}, nil
@@ -203,6 +236,7 @@ func LoadCorpus(dir string) ([]*Talk, error) {
 type JSONCorpus struct {
 	Name       string `json:"name"`
 	Source     string `json:"source"`
+	Language   string `json:"language"`
 	Text       string `json:"text"`
 	Sentences  int    `json:"sentences"`
 	Boundaries []int  `json:"boundaries"` // Character offsets where sentences end
@@ -231,6 +265,7 @@ func LoadJSONCorpus(path string) (*Talk, error) {
 		Source:    corpus.Source,
 		Speaker:   corpus.Name,
 		Title:     corpus.Name,
+		Language:  corpus.Language,
 		RawText:   corpus.Text,
 		Sentences: sentences,
 	}, nil
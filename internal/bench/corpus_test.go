@@ -3,7 +3,9 @@ package bench
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
+	"unicode/utf8"
 )
 
 func TestParseHeader(t *testing.T) {
@@ -28,6 +30,18 @@ Hello world.`,
 			},
 			wantBody: "Hello world.",
 		},
+		{
+			name: "language",
+			input: `# Source: https://example.com/talk
+# Language: ja
+
+こんにちは。`,
+			want: Header{
+				Source:   "https://example.com/talk",
+				Language: "ja",
+			},
+			wantBody: "こんにちは。",
+		},
 		{
 			name: "missing source",
 			input: `# Speaker: John Doe
@@ -177,3 +191,52 @@ Hello.`
 		t.Errorf("got %d talks, want 2", len(talks))
 	}
 }
+
+func TestParseSentences_FullWidth(t *testing.T) {
+	text := "今天很好。我们走吧！「好。」他说‼ Ok. Done"
+	got := ParseSentences(text)
+	want := []string{"今天很好。", "我们走吧！", "「好。」", "他说‼", "Ok.", "Done"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d sentences %+v, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		if got[i].Text != want[i] {
+			t.Errorf("sentence[%d] = %q, want %q", i, got[i].Text, want[i])
+		}
+	}
+}
+
+func TestLoadCorpus_CJK(t *testing.T) {
+	talks, err := LoadCorpus(filepath.Join("..", "..", "testdata", "cjk"))
+	if err != nil {
+		t.Fatalf("LoadCorpus() error = %v", err)
+	}
+	if got, want := Languages(talks), []string{"ja", "th", "zh"}; !slices.Equal(got, want) {
+		t.Fatalf("Languages = %q, want %q", got, want)
+	}
+
+	for _, talk := range talks {
+		if len(talk.Sentences) == 0 {
+			t.Errorf("%s: no sentences", talk.ID)
+		}
+		for _, s := range talk.Sentences {
+			if s.End < len(talk.RawText) && !utf8.RuneStart(talk.RawText[s.End]) {
+				t.Errorf("%s: boundary %d splits a character", talk.ID, s.End)
+			}
+		}
+		if talk.Language == "th" {
+			continue
+		}
+
+		// Punctuation marks every boundary of the Chinese and Japanese texts
+		parsed := ParseSentences(talk.RawText)
+		if len(parsed) != len(talk.Sentences) {
+			t.Fatalf("%s: ParseSentences found %d sentences, want %d", talk.ID, len(parsed), len(talk.Sentences))
+		}
+		for i := range parsed {
+			if parsed[i].End != talk.Sentences[i].End {
+				t.Errorf("%s: sentence %d ends at %d, gold %d", talk.ID, i, parsed[i].End, talk.Sentences[i].End)
+			}
+		}
+	}
+}
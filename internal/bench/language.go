@@ -0,0 +1,21 @@
+package bench
+
+import (
+	"maps"
+	"slices"
+)
+
+// ByLanguage groups talks by Language. Talks without a language are grouped
+// under "".
+func ByLanguage(talks []*Talk) map[string][]*Talk {
+	groups := make(map[string][]*Talk)
+	for _, talk := range talks {
+		groups[talk.Language] = append(groups[talk.Language], talk)
+	}
+	return groups
+}
+
+// Languages returns the distinct languages of talks in sorted order.
+func Languages(talks []*Talk) []string {
+	return slices.Sorted(maps.Keys(ByLanguage(talks)))
+}
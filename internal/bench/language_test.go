@@ -0,0 +1,26 @@
+package bench
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestByLanguage(t *testing.T) {
+	talks := []*Talk{
+		{ID: "a", Language: "en"},
+		{ID: "b", Language: "ja"},
+		{ID: "c"},
+		{ID: "d", Language: "en"},
+	}
+
+	groups := ByLanguage(talks)
+	if got := len(groups["en"]); got != 2 {
+		t.Errorf("got %d English talks, want 2", got)
+	}
+	if got := len(groups[""]); got != 1 {
+		t.Errorf("got %d talks without language, want 1", got)
+	}
+	if got, want := Languages(talks), []string{"", "en", "ja"}; !slices.Equal(got, want) {
+		t.Errorf("Languages = %q, want %q", got, want)
+	}
+}
@@ -8,10 +8,19 @@ import (
 
 // Config holds evaluation parameters.
 type Config struct {
-	Threshold       float32
-	Tolerance       int     // character match tolerance
-	PrecisionWeight float64
-	RecallWeight    float64
+	Threshold          float32
+	LanguageThresholds map[string]float32 // replace Threshold for talks in a language
+	Tolerance          int                // character match tolerance
+	PrecisionWeight    float64
+	RecallWeight       float64
+}
+
+// ThresholdFor returns the threshold used for talks in lang.
+func (c Config) ThresholdFor(lang string) float32 {
+	if t, ok := c.LanguageThresholds[lang]; ok {
+		return t
+	}
+	return c.Threshold
 }
 
 // DefaultConfig returns default evaluation configuration.
@@ -61,6 +70,12 @@ func Evaluate(predicted, truth []int, cfg Config) Metrics {
 	fp := len(predicted) - tp
 	fn := len(truth) - tp
 
+	return Aggregate(tp, fp, fn, cfg)
+}
+
+// Aggregate computes metrics from boundary counts, such as totals over
+// several talks.
+func Aggregate(tp, fp, fn int, cfg Config) Metrics {
 	m := Metrics{
 		TruePositives:  tp,
 		FalsePositives: fp,
@@ -86,10 +101,11 @@ func Evaluate(predicted, truth []int, cfg Config) Metrics {
 	return m
 }
 
-// EvaluateTalk runs segmentation on a talk and evaluates against ground truth.
+// EvaluateTalk runs segmentation on a talk at the threshold for its language
+// and evaluates against ground truth.
 func EvaluateTalk(ctx context.Context, seg *sat.Segmenter, talk *Talk, cfg Config) (Metrics, error) {
 	// Get predicted boundaries using the new method
-	_, predicted, err := seg.SegmentWithBoundaries(ctx, talk.RawText)
+	_, predicted, err := seg.SegmentWithBoundaries(ctx, talk.RawText, sat.CallThreshold(cfg.ThresholdFor(talk.Language)))
 	if err != nil {
 		return Metrics{}, err
 	}
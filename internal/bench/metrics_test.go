@@ -107,3 +107,15 @@ func TestEvaluateTalk(t *testing.T) {
 		t.Errorf("Precision = %v, want >= 0.5", metrics.Precision)
 	}
 }
+
+func TestConfig_ThresholdFor(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.LanguageThresholds = map[string]float32{"ja": 0.2}
+
+	if got := cfg.ThresholdFor("ja"); got != 0.2 {
+		t.Errorf("ThresholdFor(ja) = %v, want 0.2", got)
+	}
+	if got := cfg.ThresholdFor("en"); got != cfg.Threshold {
+		t.Errorf("ThresholdFor(en) = %v, want %v", got, cfg.Threshold)
+	}
+}
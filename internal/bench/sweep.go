@@ -22,56 +22,96 @@ func SweepThresholds(min, max, step float32) []float32 {
 	return thresholds
 }
 
+// sweepCacheBytes bounds the logit cache used by Sweep. Talks are evaluated
+// one at a time, so it only needs to hold the logits of the longest talk.
+const sweepCacheBytes = 64 << 20
+
+// SweepReport holds the results of a sweep over all talks and over the talks
+// of each language, each sorted by weighted score.
+type SweepReport struct {
+	Overall []SweepResult
+
+	// ByLanguage is keyed by Talk.Language, so that each language gets its
+	// own optimal threshold.
+	ByLanguage map[string][]SweepResult
+}
+
 // Sweep evaluates multiple thresholds and returns results sorted by weighted score.
+// The model is loaded once and runs once per talk: every threshold is
+// evaluated from the cached logits of the talk.
 func Sweep(ctx context.Context, talks []*Talk, modelPath, tokenizerPath string, cfg Config, thresholds []float32) ([]SweepResult, error) {
-	var results []SweepResult
+	report, err := SweepLanguages(ctx, talks, modelPath, tokenizerPath, cfg, thresholds)
+	if err != nil {
+		return nil, err
+	}
+	return report.Overall, nil
+}
 
-	for _, threshold := range thresholds {
-		seg, err := sat.New(modelPath, tokenizerPath, sat.WithThreshold(threshold))
-		if err != nil {
-			return nil, err
-		}
+// SweepLanguages is like Sweep, but also reports the metrics of the talks of
+// each language, computed in the same pass over the corpus.
+func SweepLanguages(ctx context.Context, talks []*Talk, modelPath, tokenizerPath string, cfg Config, thresholds []float32) (SweepReport, error) {
+	seg, err := newSweepSegmenter(modelPath, tokenizerPath)
+	if err != nil {
+		return SweepReport{}, err
+	}
+	defer func() { _ = seg.Close() }()
+
+	return sweepTalks(ctx, seg, talks, cfg, thresholds)
+}
+
+// newSweepSegmenter loads the model with a logit cache, so that a talk is
+// run through the model once however many thresholds are evaluated.
+func newSweepSegmenter(modelPath, tokenizerPath string) (*sat.Segmenter, error) {
+	return sat.New(modelPath, tokenizerPath, sat.WithLogitCache(sat.NewLogitCache(sweepCacheBytes)))
+}
+
+// sweepTalks evaluates thresholds on talks with seg, totalling the counts of
+// each language and of the whole corpus. Every threshold replaces any
+// language-specific one in cfg.
+func sweepTalks(ctx context.Context, seg *sat.Segmenter, talks []*Talk, cfg Config, thresholds []float32) (SweepReport, error) {
+	cfg.LanguageThresholds = nil
 
-		// Aggregate metrics across all talks
-		var totalTP, totalFP, totalFN int
-		for _, talk := range talks {
+	overall := make([]Metrics, len(thresholds))
+	byLanguage := make(map[string][]Metrics)
+	for _, talk := range talks {
+		totals, ok := byLanguage[talk.Language]
+		if !ok {
+			totals = make([]Metrics, len(thresholds))
+			byLanguage[talk.Language] = totals
+		}
+		for i, threshold := range thresholds {
 			cfg.Threshold = threshold
 			m, err := EvaluateTalk(ctx, seg, talk, cfg)
 			if err != nil {
-				_ = seg.Close()
-				return nil, err
+				return SweepReport{}, err
+			}
+			for _, t := range []*Metrics{&overall[i], &totals[i]} {
+				t.TruePositives += m.TruePositives
+				t.FalsePositives += m.FalsePositives
+				t.FalseNegatives += m.FalseNegatives
 			}
-			totalTP += m.TruePositives
-			totalFP += m.FalsePositives
-			totalFN += m.FalseNegatives
 		}
+	}
 
-		_ = seg.Close()
-
-		// Compute aggregate metrics
-		agg := Metrics{
-			TruePositives:  totalTP,
-			FalsePositives: totalFP,
-			FalseNegatives: totalFN,
-		}
-		if totalTP+totalFP > 0 {
-			agg.Precision = float64(totalTP) / float64(totalTP+totalFP)
-		}
-		if totalTP+totalFN > 0 {
-			agg.Recall = float64(totalTP) / float64(totalTP+totalFN)
-		}
-		if agg.Precision+agg.Recall > 0 {
-			agg.F1 = 2 * agg.Precision * agg.Recall / (agg.Precision + agg.Recall)
-		}
-		wp := cfg.PrecisionWeight
-		wr := cfg.RecallWeight
-		if wp+wr > 0 {
-			agg.WeightedScore = (wp*agg.Precision + wr*agg.Recall) / (wp + wr)
-		}
+	report := SweepReport{
+		Overall:    sweepResults(overall, cfg, thresholds),
+		ByLanguage: make(map[string][]SweepResult, len(byLanguage)),
+	}
+	for lang, totals := range byLanguage {
+		report.ByLanguage[lang] = sweepResults(totals, cfg, thresholds)
+	}
+	return report, nil
+}
 
+// sweepResults computes the metrics of each threshold from its totals and
+// sorts them by weighted score.
+func sweepResults(totals []Metrics, cfg Config, thresholds []float32) []SweepResult {
+	results := make([]SweepResult, 0, len(thresholds))
+	for i, threshold := range thresholds {
+		totalTP, totalFP, totalFN := totals[i].TruePositives, totals[i].FalsePositives, totals[i].FalseNegatives
 		results = append(results, SweepResult{
 			Threshold: threshold,
-			Metrics:   agg,
+			Metrics:   Aggregate(totalTP, totalFP, totalFN, cfg),
 		})
 	}
 
@@ -80,5 +120,5 @@ func Sweep(ctx context.Context, talks []*Talk, modelPath, tokenizerPath string,
 		return results[i].Metrics.WeightedScore > results[j].Metrics.WeightedScore
 	})
 
-	return results, nil
+	return results
 }
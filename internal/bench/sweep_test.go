@@ -1,7 +1,11 @@
 package bench
 
 import (
+	"context"
 	"testing"
+
+	sat "github.com/jamesainslie/go-sat"
+	"github.com/jamesainslie/go-sat/sattest"
 )
 
 func TestSweepThresholds(t *testing.T) {
@@ -21,3 +25,58 @@ func TestSweepThresholds(t *testing.T) {
 		}
 	}
 }
+
+func TestSweepTalks_Languages(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	backend := sattest.NewSentenceBackend(tok)
+	seg, err := sat.NewWithBackend(backend, tok, sat.WithLogitCache(sat.NewLogitCache(sweepCacheBytes)))
+	if err != nil {
+		t.Fatalf("NewWithBackend() error = %v", err)
+	}
+	defer func() { _ = seg.Close() }()
+
+	talk := func(lang, text string) *Talk {
+		return &Talk{ID: lang, Language: lang, RawText: text, Sentences: ParseSentences(text)}
+	}
+	talks := []*Talk{
+		talk("en", "One. Two. Three."),
+		talk("de", "Eins. Zwei"),
+		talk("en", "Four. Five."),
+	}
+	thresholds := []float32{0.5, 0.99999}
+
+	report, err := sweepTalks(context.Background(), seg, talks, DefaultConfig(), thresholds)
+	if err != nil {
+		t.Fatalf("sweepTalks() error = %v", err)
+	}
+	if got := backend.Rows(); got != len(talks) {
+		t.Errorf("model ran on %d rows, want one per talk (%d)", got, len(talks))
+	}
+
+	// Totals per language add up to the overall totals
+	for i, threshold := range thresholds {
+		overall := find(t, report.Overall, threshold).Metrics
+		sum := 0
+		for _, lang := range []string{"en", "de"} {
+			sum += find(t, report.ByLanguage[lang], threshold).Metrics.TruePositives
+		}
+		if overall.TruePositives != sum {
+			t.Errorf("threshold %d: overall TP %d, languages sum to %d", i, overall.TruePositives, sum)
+		}
+	}
+	if len(report.ByLanguage) != 2 {
+		t.Errorf("got languages %v, want en and de", report.ByLanguage)
+	}
+}
+
+// find returns the result for threshold.
+func find(t *testing.T, results []SweepResult, threshold float32) SweepResult {
+	t.Helper()
+	for _, r := range results {
+		if r.Threshold == threshold {
+			return r
+		}
+	}
+	t.Fatalf("no result for threshold %v", threshold)
+	return SweepResult{}
+}
@@ -0,0 +1,13 @@
+// Package punct classifies punctuation shared by the segmenter and the
+// benchmark corpora, so both agree on where sentences end.
+package punct
+
+// IsFullWidthTerminal reports whether r ends a sentence in Chinese or
+// Japanese text, where no space follows it.
+func IsFullWidthTerminal(r rune) bool {
+	switch r {
+	case '。', '｡', '！', '？', '‼', '⁇', '⁈', '⁉', '︒', '︕', '︖':
+		return true
+	}
+	return false
+}
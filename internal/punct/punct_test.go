@@ -0,0 +1,16 @@
+package punct
+
+import "testing"
+
+func TestIsFullWidthTerminal(t *testing.T) {
+	for _, r := range "。｡！？‼⁇⁈⁉︒︕︖" {
+		if !IsFullWidthTerminal(r) {
+			t.Errorf("IsFullWidthTerminal(%q) = false, want true", r)
+		}
+	}
+	for _, r := range ".!?、，」a我" {
+		if IsFullWidthTerminal(r) {
+			t.Errorf("IsFullWidthTerminal(%q) = true, want false", r)
+		}
+	}
+}
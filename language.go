@@ -0,0 +1,130 @@
+package sat
+
+import (
+	"strings"
+	"unicode"
+)
+
+// languageRules maps lowercase language codes to the call options applied
+// to text in that language.
+type languageRules map[string][]CallOption
+
+// normalizeLanguage returns lang in lowercase with "-" separating its
+// subtags, e.g. "pt-br" for "pt_BR".
+func normalizeLanguage(lang string) string {
+	return strings.ToLower(strings.ReplaceAll(lang, "_", "-"))
+}
+
+// lookup returns the rules for lang, falling back from a regional code such
+// as "pt-BR" to its base language.
+func (lr languageRules) lookup(lang string) ([]CallOption, bool) {
+	lang = normalizeLanguage(lang)
+	if rules, ok := lr[lang]; ok {
+		return rules, true
+	}
+	if base, _, found := strings.Cut(lang, "-"); found {
+		rules, ok := lr[base]
+		return rules, ok
+	}
+	return nil, false
+}
+
+// CallLanguage tells a call that its text is in lang, an ISO 639-1 code such
+// as "en" or "ja", selecting the options registered for it with
+// WithLanguageOptions. It takes precedence over WithLanguageDetection.
+func CallLanguage(lang string) CallOption {
+	return func(c *callConfig) {
+		c.language = lang
+	}
+}
+
+// textConfig returns the settings of a call on text: the Segmenter's
+// defaults, then the options registered for the language of text, then opts.
+// The language is the one given with CallLanguage or, with language
+// detection enabled, the one DetectLanguage reports.
+func (s *Segmenter) textConfig(text string, opts []CallOption) (callConfig, error) {
+	cc, err := s.callConfig(opts)
+	if err != nil || len(s.languages) == 0 {
+		return cc, err
+	}
+
+	lang := cc.language
+	if lang == "" && cc.detectLanguage {
+		lang = DetectLanguage(text)
+	}
+	rules, ok := s.languages.lookup(lang)
+	if !ok {
+		return cc, nil
+	}
+
+	return s.callConfig(append(rules[:len(rules):len(rules)], opts...))
+}
+
+// scriptLanguages maps scripts that identify a language, or the language
+// most likely to be segmented, to its code.
+var scriptLanguages = []struct {
+	table *unicode.RangeTable
+	lang  string
+}{
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Hangul, "ko"},
+	{unicode.Han, "zh"},
+	{unicode.Thai, "th"},
+	{unicode.Lao, "lo"},
+	{unicode.Khmer, "km"},
+	{unicode.Myanmar, "my"},
+	{unicode.Tibetan, "bo"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Greek, "el"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Armenian, "hy"},
+	{unicode.Georgian, "ka"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Bengali, "bn"},
+	{unicode.Tamil, "ta"},
+	{unicode.Telugu, "te"},
+	{unicode.Ethiopic, "am"},
+}
+
+// DetectLanguage guesses the language of text from the scripts of its
+// letters. Text in Latin script, or without letters, returns "", since
+// its script does not tell its languages apart. Japanese is recognized by
+// kana even when kanji outnumber them; other scripts shared by several
+// languages map to the most widely used one, e.g. Cyrillic to "ru".
+func DetectLanguage(text string) string {
+	counts := make(map[string]int)
+	latin, letters := 0, 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.Is(unicode.Latin, r) {
+			latin++
+			continue
+		}
+		for _, sl := range scriptLanguages {
+			if unicode.Is(sl.table, r) {
+				counts[sl.lang]++
+				break
+			}
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+
+	// Kana mark Japanese text whatever the share of kanji
+	if counts["ja"] > 0 && counts["ja"]+counts["zh"] > latin {
+		return "ja"
+	}
+	best, bestCount := "", latin
+	for _, sl := range scriptLanguages {
+		if n := counts[sl.lang]; n > bestCount {
+			best, bestCount = sl.lang, n
+		}
+	}
+	return best
+}
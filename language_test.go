@@ -0,0 +1,109 @@
+package sat
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/jamesainslie/go-sat/sattest"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"", ""},
+		{"123 ...", ""},
+		{"Hello there. How are you?", ""},
+		{"今日は良い天気ですね。", "ja"},
+		{"東京大学に行く。", "ja"},
+		{"我们明天见。", "zh"},
+		{"안녕하세요. 반갑습니다.", "ko"},
+		{"สวัสดีครับ วันนี้อากาศดี", "th"},
+		{"Привет, как дела?", "ru"},
+		{"Γεια σου. Τι κάνεις;", "el"},
+		{"The word 東京 is Japanese.", ""},
+	}
+	for _, tc := range tests {
+		if got := DetectLanguage(tc.text); got != tc.want {
+			t.Errorf("DetectLanguage(%q) = %q, want %q", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestLanguageOptions(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	backend := sattest.NewSentenceBackend(tok)
+	backend.BoundaryLogit = 1 // sigmoid(1) ≈ 0.73
+	seg, err := NewWithBackend(backend, tok,
+		WithLanguageOptions("el", CallThreshold(0.9)),
+		WithLanguageOptions("PT", CallThreshold(0.9), CallStripWhitespace(true)),
+	)
+	if err != nil {
+		t.Fatalf("NewWithBackend() failed: %v", err)
+	}
+	defer func() { _ = seg.Close() }()
+
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		text string
+		opts []CallOption
+		want int
+	}{
+		{"no language", "One. Two.", nil, 2},
+		{"unregistered language", "One. Two.", []CallOption{CallLanguage("en")}, 2},
+		{"registered language", "One. Two.", []CallOption{CallLanguage("el")}, 1},
+		{"regional code", "One. Two.", []CallOption{CallLanguage("pt_BR")}, 1},
+		{"call options win", "One. Two.", []CallOption{CallLanguage("el"), CallThreshold(0.5)}, 2},
+		{"no detection by default", "Ένα. Δύο.", nil, 2},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := seg.Segment(ctx, tc.text, tc.opts...)
+			if err != nil {
+				t.Fatalf("Segment failed: %v", err)
+			}
+			if len(got) != tc.want {
+				t.Errorf("Segment = %q, want %d sentences", got, tc.want)
+			}
+		})
+	}
+
+	if complete, _, err := seg.IsComplete(ctx, "Done.", CallLanguage("el")); err != nil || complete {
+		t.Errorf("IsComplete with language threshold 0.9 = %v, %v; want false", complete, err)
+	}
+}
+
+func TestLanguageDetection(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	backend := sattest.NewSentenceBackend(tok)
+	backend.BoundaryLogit = 1
+	seg, err := NewWithBackend(backend, tok,
+		WithLanguageOptions("el", CallThreshold(0.9)),
+		WithLanguageDetection(true),
+	)
+	if err != nil {
+		t.Fatalf("NewWithBackend() failed: %v", err)
+	}
+	defer func() { _ = seg.Close() }()
+
+	texts := []string{"Ένα. Δύο.", "One. Two."}
+	batch, err := seg.SegmentBatch(context.Background(), texts)
+	if err != nil {
+		t.Fatalf("SegmentBatch failed: %v", err)
+	}
+	if want := [][]string{{"Ένα. Δύο."}, {"One.", " Two."}}; !slices.EqualFunc(batch, want, slices.Equal) {
+		t.Errorf("SegmentBatch = %q, want %q", batch, want)
+	}
+
+	// An explicit language overrides detection
+	got, err := seg.Segment(context.Background(), texts[0], CallLanguage("en"))
+	if err != nil {
+		t.Fatalf("Segment failed: %v", err)
+	}
+	if len(got) != 2 {
+		t.Errorf("Segment with CallLanguage(\"en\") = %q, want 2 sentences", got)
+	}
+}
@@ -3,29 +3,109 @@ package sat
 import (
 	"log/slog"
 	"runtime"
+
+	"github.com/jamesainslie/go-sat/inference"
 )
 
 // Option configures a Segmenter.
 type Option func(*config)
 
 type config struct {
-	threshold float32
-	poolSize  int
-	logger    *slog.Logger
+	threshold          float32
+	paragraphThreshold float32
+	newlineLabel       int
+	lengths            lengthConstraint
+	stripWhitespace    bool
+	dropEmpty          bool
+	attachClosing      bool
+	skipWhitespace     bool
+	languages          languageRules
+	detectLanguage     bool
+	adaptive           adaptiveRule
+	cache              *LogitCache
+	poolSize           int
+	batchSize          int
+	parallelism        int
+	chunkSize          int
+	chunkStride        int
+	chunkOverlap       int
+	stitching          Stitching
+	specialTokens      bool
+	label              int
+	session            inference.SessionConfig
+	native             *inference.NativeConfig
+	adapter            string
+	logger             *slog.Logger
 }
 
 func defaultConfig() config {
 	return config{
-		threshold: 0.025,
-		poolSize:  runtime.NumCPU(),
-		logger:    slog.Default(),
+		threshold:          0.025,
+		paragraphThreshold: 0.5,
+		newlineLabel:       -1,
+		poolSize:           runtime.NumCPU(),
+		batchSize:          8,
+		parallelism:        1,
+		chunkOverlap:       -1,
+		stitching:          StitchMean,
+		specialTokens:      true,
+		logger:             slog.Default(),
 	}
 }
 
-// WithThreshold sets the boundary detection threshold (default: 0.025).
+// preoptimized reports whether ONNX Runtime sessions load a preoptimized
+// model set with WithPreoptimizedModel in place of the model.
+func (c config) preoptimized() bool {
+	return c.native == nil && c.session.PreoptimizedModelPath != ""
+}
+
+// WithThreshold sets the boundary detection threshold (default: 0.025). It
+// replaces an adaptive rule set earlier in the option list.
 func WithThreshold(t float32) Option {
 	return func(c *config) {
 		c.threshold = t
+		c.adaptive = adaptiveRule{}
+	}
+}
+
+// WithCharsPerSentence replaces the fixed threshold with a per-document
+// choice: each document is split into about one sentence per n characters of
+// trimmed text, at its most probable boundaries however low their
+// probabilities. It applies to Segment, SegmentWithBoundaries,
+// SegmentDetailed, SegmentBatch and SegmentParagraphs; IsComplete and streams
+// keep the fixed threshold. A later WithThreshold restores it.
+func WithCharsPerSentence(n int) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.adaptive = adaptiveRule{kind: adaptiveCharsPerSentence, charsPerSentence: n}
+		}
+	}
+}
+
+// WithProbabilityMass is like WithCharsPerSentence, but expects as many
+// sentences as the sum of the boundary probabilities of the document's tokens,
+// which is the expected count if the model is calibrated.
+func WithProbabilityMass() Option {
+	return func(c *config) {
+		c.adaptive = adaptiveRule{kind: adaptiveProbabilityMass}
+	}
+}
+
+// WithParagraphThreshold sets the newline probability above which
+// SegmentParagraphs starts a new paragraph (default: 0.5, as in wtpsplit).
+func WithParagraphThreshold(t float32) Option {
+	return func(c *config) {
+		c.paragraphThreshold = t
+	}
+}
+
+// WithNewlineLabel selects the label column of a multi-label model that
+// predicts newlines, used by SegmentParagraphs to find paragraph breaks. By
+// default the sentence boundary probability is used instead. New fails with
+// ErrInvalidLabel if the model has no such label.
+func WithNewlineLabel(label int) Option {
+	return func(c *config) {
+		c.newlineLabel = label
 	}
 }
 
@@ -38,6 +118,280 @@ func WithPoolSize(n int) Option {
 	}
 }
 
+// WithBatchSize sets the maximum number of chunks packed into a single model
+// run (default: 8). Larger batches improve throughput when segmenting many
+// texts at the cost of memory per run.
+func WithBatchSize(n int) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.batchSize = n
+		}
+	}
+}
+
+// WithParallelism sets how many pooled sessions a single call may use at
+// once (default: 1). With n > 1 the chunks of a long text, or of the texts
+// passed to SegmentBatch, are spread over up to n sessions, capped at the
+// pool size. This lowers the latency of one long document at the cost of
+// sessions available to concurrent callers.
+func WithParallelism(n int) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.parallelism = n
+		}
+	}
+}
+
+// WithChunkSize sets the maximum number of text tokens per model run
+// (default: the backend's maximum sequence length, less two positions for
+// <s> and </s>). Longer texts are split into overlapping chunks. Values above
+// the backend's limit are capped to it.
+func WithChunkSize(n int) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.chunkSize = n
+		}
+	}
+}
+
+// WithChunkStride sets the number of tokens between the starts of
+// consecutive chunks. It takes precedence over WithChunkOverlap and is capped
+// at the chunk size.
+func WithChunkStride(n int) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.chunkStride = n
+		}
+	}
+}
+
+// WithChunkOverlap sets the number of tokens shared by consecutive chunks
+// (default: 64, or a quarter of the chunk size if that is smaller). It is
+// capped so that chunks advance by at least one token.
+func WithChunkOverlap(n int) Option {
+	return func(c *config) {
+		if n >= 0 {
+			c.chunkOverlap = n
+		}
+	}
+}
+
+// WithStitching sets how predictions are combined where chunks overlap
+// (default: StitchMean).
+func WithStitching(mode Stitching) Option {
+	return func(c *config) {
+		c.stitching = mode
+	}
+}
+
+// WithSpecialTokens sets whether each chunk is framed with the <s> and </s>
+// tokens the model was trained with (default: true). Disable it only for
+// models exported to expect bare token IDs.
+func WithSpecialTokens(enabled bool) Option {
+	return func(c *config) {
+		c.specialTokens = enabled
+	}
+}
+
+// WithMinLength sets the minimum length of a sentence, in the unit set by
+// WithLengthUnit. With a minimum or maximum set, split points are chosen by
+// constrained decoding instead of thresholding alone: among segmentations
+// that fit the limits, the one whose splits best match the boundary
+// probabilities. A zero or negative n removes the limit.
+func WithMinLength(n int) Option {
+	return func(c *config) {
+		c.lengths.min = max(n, 0)
+	}
+}
+
+// WithMaxLength sets the maximum length of a sentence, in the unit set by
+// WithLengthUnit. Only a single token longer than n, with any closing
+// punctuation or whitespace that WithAttachClosing or
+// WithSkipWhitespaceTokens keep beside it, can produce a longer sentence.
+// See WithMinLength. A zero or negative n removes the limit.
+func WithMaxLength(n int) Option {
+	return func(c *config) {
+		c.lengths.max = max(n, 0)
+	}
+}
+
+// WithLengthUnit sets the unit of WithMinLength and WithMaxLength (default:
+// LengthChars).
+func WithLengthUnit(unit LengthUnit) Option {
+	return func(c *config) {
+		c.lengths.unit = unit
+	}
+}
+
+// WithStripWhitespace sets whether sentences have leading and trailing
+// whitespace removed (default: false). Their offsets are narrowed to match,
+// so consecutive sentences are no longer contiguous, and sentences that are
+// only whitespace are dropped.
+func WithStripWhitespace(strip bool) Option {
+	return func(c *config) {
+		c.stripWhitespace = strip
+	}
+}
+
+// WithDropEmpty sets whether sentences that are only whitespace, such as a
+// trailing newline, are dropped (default: false). The remaining sentences
+// keep their offsets.
+func WithDropEmpty(drop bool) Option {
+	return func(c *config) {
+		c.dropEmpty = drop
+	}
+}
+
+// WithAttachClosing sets whether closing quotes and brackets that directly
+// follow a sentence boundary are moved into the sentence they close
+// (default: false), so that `He said "Hi."` is not split before the final
+// quote.
+func WithAttachClosing(attach bool) Option {
+	return func(c *config) {
+		c.attachClosing = attach
+	}
+}
+
+// WithSkipWhitespaceTokens sets whether a boundary predicted on a token that
+// is only whitespace moves back to the end of the preceding token (default:
+// false), so the whitespace starts the next sentence instead of ending this
+// one.
+func WithSkipWhitespaceTokens(skip bool) Option {
+	return func(c *config) {
+		c.skipWhitespace = skip
+	}
+}
+
+// WithLanguageOptions registers call options, such as CallThreshold or
+// CallMaxLength, for text in lang, an ISO 639-1 code such as "ja". They apply
+// on top of the Segmenter's configuration when a call names the language
+// with CallLanguage or, with WithLanguageDetection, when DetectLanguage
+// reports it; options passed to the call still take precedence. A regional
+// code such as "pt-BR" falls back to the options of its base language.
+// Registering a language again replaces its options.
+func WithLanguageOptions(lang string, opts ...CallOption) Option {
+	return func(c *config) {
+		if c.languages == nil {
+			c.languages = make(languageRules)
+		}
+		c.languages[normalizeLanguage(lang)] = opts
+	}
+}
+
+// WithLanguageDetection sets whether calls without CallLanguage detect the
+// language of their text with DetectLanguage to select the options
+// registered with WithLanguageOptions (default: false).
+func WithLanguageDetection(detect bool) Option {
+	return func(c *config) {
+		c.detectLanguage = detect
+	}
+}
+
+// WithLogitCache stores the model outputs for each text in cache, so that
+// segmenting the same text again, for example with other call options, does
+// not run the model. The cache may be shared by several Segmenters.
+func WithLogitCache(cache *LogitCache) Option {
+	return func(c *config) {
+		c.cache = cache
+	}
+}
+
+// WithSentenceLabel selects which of the model's label columns marks sentence
+// boundaries (default: 0). Models with a single output have only label 0;
+// multi-label checkpoints may put auxiliary heads, such as newline or
+// punctuation predictions, in other columns. New fails with ErrInvalidLabel
+// if the model has no such label.
+func WithSentenceLabel(label int) Option {
+	return func(c *config) {
+		c.label = label
+	}
+}
+
+// WithIntraOpThreads sets the number of threads each ONNX session uses within
+// an operator (default: chosen by ONNX Runtime, typically one per core). Every
+// pooled session has its own thread pool, so with WithPoolSize(n) a value of
+// about runtime.NumCPU()/n avoids oversubscribing the CPU.
+func WithIntraOpThreads(n int) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.session.IntraOpThreads = n
+		}
+	}
+}
+
+// WithInterOpThreads sets the number of threads each ONNX session uses to run
+// independent operators in parallel (default: chosen by ONNX Runtime). It only
+// has an effect with WithExecutionMode(inference.ExecutionModeParallel).
+func WithInterOpThreads(n int) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.session.InterOpThreads = n
+		}
+	}
+}
+
+// WithGraphOptimizationLevel sets how much ONNX Runtime optimizes the model
+// graph when creating each session (default: all optimizations).
+func WithGraphOptimizationLevel(level inference.GraphOptimizationLevel) Option {
+	return func(c *config) {
+		c.session.GraphOptimization = level
+	}
+}
+
+// WithExecutionMode sets whether ONNX Runtime runs independent operators
+// sequentially or in parallel (default: sequential).
+func WithExecutionMode(mode inference.ExecutionMode) Option {
+	return func(c *config) {
+		c.session.ExecutionMode = mode
+	}
+}
+
+// WithPreoptimizedModel loads an already optimized copy of the model from
+// path, when it exists, and skips graph optimization so that sessions start
+// faster. The file is only read, never written: produce it ahead of time,
+// e.g. with onnxruntime's SessionOptions.optimized_model_filepath in Python.
+// If path does not exist the model is loaded and optimized as usual.
+//
+// Since the copy replaces the model, it only applies to New without a model
+// variant adapter. NewFromBytes, NewFromReader and NewFromFS fail with
+// ErrInvalidModel, and a model variant set with WithAdapter fails with
+// ErrInvalidAdapter. The native backend ignores it.
+func WithPreoptimizedModel(path string) Option {
+	return func(c *config) {
+		c.session.PreoptimizedModelPath = path
+	}
+}
+
+// WithNativeBackend runs the model with the pure Go inference backend instead
+// of ONNX Runtime, so no shared library is needed. The model path passed to New
+// must then be a .safetensors checkpoint or an ONNX file whose initializers
+// keep the PyTorch parameter names. The pure Go backend is slower than ONNX
+// Runtime but works in static and cross-compiled builds.
+func WithNativeBackend(cfg inference.NativeConfig) Option {
+	return func(c *config) {
+		c.native = &cfg
+	}
+}
+
+// WithAdapter adapts the model to a style or domain, such as the lyrics,
+// verse or legal adapters published with wtpsplit.
+//
+// An adapter ending in .safetensors is a LoRA weights file, merged into the
+// model when it is loaded; the scaling is read from an adapter_config.json
+// beside it, if present. This requires WithNativeBackend, since ONNX Runtime
+// cannot modify a model's weights. Any other adapter names a variant of the
+// model with the adapter already merged, exported beside it: "legal" selects
+// "model.legal.onnx" for "model.onnx". Variants need a model path, so
+// NewFromBytes and NewFromReader accept only weights files.
+//
+// New fails with ErrInvalidAdapter if the adapter cannot be applied, or
+// ErrModelNotFound if the variant does not exist.
+func WithAdapter(adapter string) Option {
+	return func(c *config) {
+		c.adapter = adapter
+	}
+}
+
 // WithLogger sets the logger (default: slog.Default()).
 func WithLogger(l *slog.Logger) Option {
 	return func(c *config) {
@@ -0,0 +1,112 @@
+package sat
+
+import (
+	"context"
+	"slices"
+
+	"github.com/jamesainslie/go-sat/tokenizer"
+)
+
+// Paragraph is a run of consecutive sentences that ends at a paragraph
+// break.
+//
+// Byte offsets index the original string, so Text == text[Start:End].
+// Consecutive paragraphs are contiguous, and the sentences of a paragraph
+// cover its text exactly, unless whitespace is stripped or whitespace-only
+// sentences are dropped.
+type Paragraph struct {
+	// Text is the paragraph exactly as it appears in the input, including
+	// any surrounding whitespace.
+	Text string
+
+	// Start and End are byte offsets of the paragraph in the input.
+	Start int
+	End   int
+
+	// Sentences are the sentences of the paragraph, with offsets into the
+	// full input.
+	Sentences []Sentence
+}
+
+// SegmentParagraphs splits text into paragraphs, and each paragraph into
+// sentences, following wtpsplit's do_paragraph_segmentation. A paragraph
+// ends after every token whose newline probability exceeds the paragraph
+// threshold (see WithParagraphThreshold). The newline probability comes from
+// the label set with WithNewlineLabel, or from the sentence boundary
+// probability if none is set. Within a paragraph, sentences are split as by
+// SegmentDetailed with the same call options; every paragraph break is also
+// a sentence break. Paragraphs left without sentences are dropped.
+func (s *Segmenter) SegmentParagraphs(ctx context.Context, text string, opts ...CallOption) ([]Paragraph, error) {
+	cc, err := s.textConfig(text, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, labelProbs, err := s.tokenLabelProbabilities(ctx, text)
+	if err != nil || len(tokens) == 0 {
+		return nil, err
+	}
+
+	newlineLabel := s.label
+	if s.newlineLabel >= 0 {
+		newlineLabel = s.newlineLabel
+	}
+
+	sentProbs := labelColumn(labelProbs, s.numLabels, s.label)
+	newlineProbs := labelColumn(labelProbs, s.numLabels, newlineLabel)
+	breaks := thresholdSplits(newlineProbs, s.paragraphThreshold)
+
+	splits, sentProbs := cc.adjustSplits(text, tokens, sentProbs, cc.documentSplits(text, tokens, sentProbs))
+	return cc.finishParagraphs(text, buildParagraphs(text, tokens, sentProbs, splits, breaks)), nil
+}
+
+// buildParagraphs splits text into sentences after each token index in
+// splits or breaks (both ascending), and groups the sentences into
+// paragraphs that end at the breaks.
+func buildParagraphs(text string, tokens []tokenizer.TokenInfo, probs []float32, splits, breaks []int) []Paragraph {
+	isBreak := make(map[int]bool, len(breaks))
+	for _, i := range breaks {
+		isBreak[i] = true
+	}
+
+	splits = slices.Clone(splits)
+	for _, i := range breaks {
+		if !slices.Contains(splits, i) {
+			splits = append(splits, i)
+		}
+	}
+	slices.Sort(splits)
+
+	var (
+		paragraphs []Paragraph
+		current    []Sentence
+	)
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		start, end := current[0].Start, current[len(current)-1].End
+		paragraphs = append(paragraphs, Paragraph{
+			Text:      text[start:end],
+			Start:     start,
+			End:       end,
+			Sentences: current,
+		})
+		current = nil
+	}
+
+	for _, sent := range buildSentences(text, tokens, probs, splits) {
+		current = append(current, sent)
+		// A break on a token that covers no text cannot end a sentence of its
+		// own, so look for one anywhere in the sentence
+		for i := sent.TokenStart; i < sent.TokenEnd; i++ {
+			if isBreak[i] {
+				flush()
+				break
+			}
+		}
+	}
+	flush()
+
+	return paragraphs
+}
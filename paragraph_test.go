@@ -0,0 +1,132 @@
+package sat
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/jamesainslie/go-sat/sattest"
+)
+
+func TestBuildParagraphs(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+
+	text := "One. Two. Three! Four. Five"
+	tokens := tok.Encode(text)
+	probs := make([]float32, len(tokens))
+	var breaks []int
+	for i, tk := range tokens {
+		switch tk.Text {
+		case ".":
+			probs[i] = 0.9
+		case "!":
+			// Below the sentence threshold, but a paragraph break
+			probs[i] = 0.01
+			breaks = append(breaks, i)
+		}
+	}
+
+	got := buildParagraphs(text, tokens, probs, thresholdSplits(probs, 0.5), breaks)
+
+	want := [][]string{
+		{"One.", " Two.", " Three!"},
+		{" Four.", " Five"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d paragraphs, want %d: %+v", len(got), len(want), got)
+	}
+	end := 0
+	for i, para := range got {
+		var sentences []string
+		for _, sent := range para.Sentences {
+			sentences = append(sentences, sent.Text)
+		}
+		if !slices.Equal(sentences, want[i]) {
+			t.Errorf("paragraph %d sentences = %q, want %q", i, sentences, want[i])
+		}
+		if para.Start != end || para.Text != text[para.Start:para.End] {
+			t.Errorf("paragraph %d: Start %d End %d Text %q do not tile the input", i, para.Start, para.End, para.Text)
+		}
+		end = para.End
+	}
+	if end != len(text) {
+		t.Errorf("paragraphs end at %d, want %d", end, len(text))
+	}
+}
+
+func TestSegmentParagraphs(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	ctx := context.Background()
+	text := "One. Two! Three. Four! Five"
+
+	paragraphTexts := func(paras []Paragraph) []string {
+		var texts []string
+		for _, p := range paras {
+			texts = append(texts, p.Text)
+		}
+		return texts
+	}
+
+	t.Run("boundary probability", func(t *testing.T) {
+		backend := sattest.NewSentenceBackend(tok)
+		backend.BoundaryLogit = 1 // sigmoid(1) ≈ 0.73
+		seg, err := NewWithBackend(backend, tok, WithParagraphThreshold(0.8))
+		if err != nil {
+			t.Fatalf("NewWithBackend() failed: %v", err)
+		}
+		defer func() { _ = seg.Close() }()
+
+		paras, err := seg.SegmentParagraphs(ctx, text)
+		if err != nil {
+			t.Fatalf("SegmentParagraphs failed: %v", err)
+		}
+		if len(paras) != 1 || len(paras[0].Sentences) != 5 {
+			t.Errorf("expected one paragraph of 5 sentences, got %+v", paras)
+		}
+	})
+
+	t.Run("newline label", func(t *testing.T) {
+		backend := sattest.NewSentenceBackend(tok)
+		backend.AuxLabels = []map[int64]bool{{int64(tok.EncodeIDs("a!")[1]): true}}
+		seg, err := NewWithBackend(backend, tok, WithNewlineLabel(1))
+		if err != nil {
+			t.Fatalf("NewWithBackend() failed: %v", err)
+		}
+		defer func() { _ = seg.Close() }()
+
+		paras, err := seg.SegmentParagraphs(ctx, text)
+		if err != nil {
+			t.Fatalf("SegmentParagraphs failed: %v", err)
+		}
+		want := []string{"One. Two!", " Three. Four!", " Five"}
+		if got := paragraphTexts(paras); !slices.Equal(got, want) {
+			t.Errorf("paragraphs = %q, want %q", got, want)
+		}
+		if len(paras) == 3 && len(paras[0].Sentences) != 2 {
+			t.Errorf("first paragraph has %d sentences, want 2", len(paras[0].Sentences))
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		seg, err := NewWithBackend(sattest.NewSentenceBackend(tok), tok)
+		if err != nil {
+			t.Fatalf("NewWithBackend() failed: %v", err)
+		}
+		defer func() { _ = seg.Close() }()
+
+		paras, err := seg.SegmentParagraphs(ctx, "")
+		if err != nil || paras != nil {
+			t.Errorf("SegmentParagraphs(\"\") = %v, %v; want nil, nil", paras, err)
+		}
+	})
+}
+
+func TestNewWithBackend_InvalidNewlineLabel(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+
+	_, err := NewWithBackend(sattest.NewSentenceBackend(tok), tok, WithNewlineLabel(1))
+	if !errors.Is(err, ErrInvalidLabel) {
+		t.Errorf("expected ErrInvalidLabel, got: %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package sat
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jamesainslie/go-sat/tokenizer"
+)
+
+// sentences splits text after each token index in splits and applies the
+// split and output rules of c.
+func (c callConfig) sentences(text string, tokens []tokenizer.TokenInfo, probs []float32, splits []int) []Sentence {
+	splits, probs = c.adjustSplits(text, tokens, probs, splits)
+	return c.finishSentences(text, buildSentences(text, tokens, probs, splits))
+}
+
+// adjustSplits moves splits off whitespace-only tokens and past closing
+// quotes and brackets, as configured. A moved boundary keeps the higher of
+// its old and new probabilities, so probs is copied if any split moves.
+func (c callConfig) adjustSplits(text string, tokens []tokenizer.TokenInfo, probs []float32, splits []int) ([]int, []float32) {
+	if !c.adjustsSplits() {
+		return splits, probs
+	}
+
+	adjusted := make([]int, 0, len(splits))
+	copied := false
+	for _, i := range splits {
+		if i < 0 || i >= len(tokens) || i >= len(probs) {
+			continue
+		}
+		j := c.splitTarget(text, tokens, len(probs), i)
+		if j < 0 {
+			continue // only whitespace before the split
+		}
+
+		if j != i && probs[i] > probs[j] {
+			if !copied {
+				probs = slices.Clone(probs)
+				copied = true
+			}
+			probs[j] = probs[i]
+		}
+		if len(adjusted) == 0 || j > adjusted[len(adjusted)-1] {
+			adjusted = append(adjusted, j)
+		}
+	}
+	return adjusted, probs
+}
+
+// adjustsSplits reports whether adjustSplits moves any splits.
+func (c callConfig) adjustsSplits() bool {
+	return c.skipWhitespaceTokens || c.attachClosing
+}
+
+// splitTarget returns the token index adjustSplits moves a split after token
+// i to, or -1 if the split is dropped. Only the first n tokens are
+// considered. A target is its own target, so adjusting twice changes
+// nothing.
+func (c callConfig) splitTarget(text string, tokens []tokenizer.TokenInfo, n, i int) int {
+	j := i
+	if c.skipWhitespaceTokens {
+		for j >= 0 && isSpaceToken(text, tokens[j]) {
+			j--
+		}
+		if j < 0 {
+			return -1
+		}
+	}
+	if c.attachClosing {
+		for j+1 < len(tokens) && j+1 < n && isClosingToken(text, tokens[j], tokens[j+1]) {
+			j++
+		}
+	}
+	return j
+}
+
+// adjustedCandidates returns the splits left after adjustSplits, for the
+// length-constrained search to choose among. A split after token j survives
+// if source[j] >= 0, in which case source[j] is the token moved onto j with
+// the highest probability and folded[j] is that probability. source is nil
+// if no split moves.
+func (c callConfig) adjustedCandidates(text string, tokens []tokenizer.TokenInfo, probs []float32) (folded []float32, source []int) {
+	if !c.adjustsSplits() {
+		return probs, nil
+	}
+
+	n := min(len(tokens), len(probs))
+	folded = make([]float32, n)
+	source = make([]int, n)
+	for i := range source {
+		source[i] = -1
+	}
+	for i := range n {
+		j := c.splitTarget(text, tokens, n, i)
+		if j < 0 {
+			continue
+		}
+		if source[j] < 0 || probs[i] > folded[j] {
+			source[j], folded[j] = i, probs[i]
+		}
+	}
+	return folded, source
+}
+
+// isSpaceToken reports whether tok covers no text other than whitespace.
+func isSpaceToken(text string, tok tokenizer.TokenInfo) bool {
+	if tok.Start >= tok.End || tok.End > len(text) {
+		return true
+	}
+	return strings.TrimSpace(text[tok.Start:tok.End]) == ""
+}
+
+// isClosingToken reports whether next directly follows prev, without
+// whitespace, and consists only of closing quotes and brackets.
+func isClosingToken(text string, prev, next tokenizer.TokenInfo) bool {
+	if next.Start != prev.End || next.Start >= next.End || next.End > len(text) {
+		return false
+	}
+	for _, r := range text[next.Start:next.End] {
+		if !isClosing(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isClosing reports whether r closes a quotation or bracket. Straight quotes
+// only count as closing when they directly follow the sentence end, which
+// isClosingToken checks.
+func isClosing(r rune) bool {
+	return unicode.In(r, unicode.Pe, unicode.Pf) || r == '"' || r == '\''
+}
+
+// finishSentences drops whitespace-only sentences and trims the rest, as
+// configured. Offsets still index text.
+func (c callConfig) finishSentences(text string, sentences []Sentence) []Sentence {
+	if !c.stripWhitespace && !c.dropEmpty {
+		return sentences
+	}
+
+	kept := sentences[:0]
+	for _, sent := range sentences {
+		if sent.Trimmed == "" {
+			continue
+		}
+		if c.stripWhitespace {
+			sent = stripSentence(text, sent)
+		}
+		kept = append(kept, sent)
+	}
+	return kept
+}
+
+// stripSentence returns sent narrowed to its trimmed text.
+func stripSentence(text string, sent Sentence) Sentence {
+	sent.RuneStart += utf8.RuneCountInString(text[sent.Start:sent.TrimmedStart])
+	sent.RuneEnd -= utf8.RuneCountInString(text[sent.TrimmedEnd:sent.End])
+	sent.Text = sent.Trimmed
+	sent.Start, sent.End = sent.TrimmedStart, sent.TrimmedEnd
+	return sent
+}
+
+// finishParagraphs applies finishSentences to the sentences of each
+// paragraph, drops paragraphs left empty and, if whitespace is stripped,
+// narrows the rest to their sentences.
+func (c callConfig) finishParagraphs(text string, paragraphs []Paragraph) []Paragraph {
+	if !c.stripWhitespace && !c.dropEmpty {
+		return paragraphs
+	}
+
+	kept := paragraphs[:0]
+	for _, para := range paragraphs {
+		para.Sentences = c.finishSentences(text, para.Sentences)
+		if len(para.Sentences) == 0 {
+			continue
+		}
+		if c.stripWhitespace {
+			para.Start, para.End = para.Sentences[0].Start, para.Sentences[len(para.Sentences)-1].End
+			para.Text = text[para.Start:para.End]
+		}
+		kept = append(kept, para)
+	}
+	return kept
+}
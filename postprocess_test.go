@@ -0,0 +1,96 @@
+package sat
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/jamesainslie/go-sat/sattest"
+	"github.com/jamesainslie/go-sat/tokenizer"
+)
+
+func TestSentences_PostProcessing(t *testing.T) {
+	text := `He said "Hi." Ok.` + "\n"
+	tokens := []tokenizer.TokenInfo{
+		{Text: "▁He", Start: 0, End: 2},
+		{Text: "▁said", Start: 2, End: 7},
+		{Text: "▁\"Hi", Start: 7, End: 11},
+		{Text: ".", Start: 11, End: 12},
+		{Text: "\"", Start: 12, End: 13},
+		{Text: "▁Ok", Start: 13, End: 16},
+		{Text: ".", Start: 16, End: 17},
+		{Text: "\n", Start: 17, End: 18},
+	}
+	probs := []float32{0, 0, 0, 0.9, 0.1, 0, 0.2, 0.8}
+	splits := []int{3, 7}
+
+	tests := []struct {
+		name string
+		cc   callConfig
+		want []string
+	}{
+		{"none", callConfig{}, []string{`He said "Hi.`, `" Ok.` + "\n"}},
+		{"attach closing", callConfig{attachClosing: true}, []string{`He said "Hi."`, " Ok.\n"}},
+		{"skip whitespace tokens", callConfig{skipWhitespaceTokens: true}, []string{`He said "Hi.`, `" Ok.`, "\n"}},
+		{"drop empty", callConfig{skipWhitespaceTokens: true, dropEmpty: true}, []string{`He said "Hi.`, `" Ok.`}},
+		{"all", callConfig{attachClosing: true, skipWhitespaceTokens: true, stripWhitespace: true}, []string{`He said "Hi."`, "Ok."}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.cc.sentences(text, tokens, probs, splits)
+			var texts []string
+			for _, sent := range got {
+				texts = append(texts, sent.Text)
+				if sent.Text != text[sent.Start:sent.End] {
+					t.Errorf("sentence %+v does not match its offsets", sent)
+				}
+				if sent.RuneEnd-sent.RuneStart != utf8.RuneCountInString(sent.Text) {
+					t.Errorf("sentence %+v has inconsistent rune offsets", sent)
+				}
+			}
+			if !slices.Equal(texts, tc.want) {
+				t.Errorf("sentences = %q, want %q", texts, tc.want)
+			}
+		})
+	}
+
+	// A moved boundary keeps the probability of the split it replaces
+	got := callConfig{skipWhitespaceTokens: true}.sentences(text, tokens, probs, splits)
+	if got[1].Probability != 0.8 {
+		t.Errorf("moved boundary probability = %v, want 0.8", got[1].Probability)
+	}
+	if probs[6] != 0.2 {
+		t.Errorf("probs modified: %v", probs)
+	}
+}
+
+func TestSegmentParagraphs_StripWhitespace(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	backend := sattest.NewSentenceBackend(tok)
+	seg, err := NewWithBackend(backend, tok, WithStripWhitespace(true))
+	if err != nil {
+		t.Fatalf("NewWithBackend() failed: %v", err)
+	}
+	defer func() { _ = seg.Close() }()
+
+	text := "One. Two.  "
+	paragraphs, err := seg.SegmentParagraphs(context.Background(), text, CallThreshold(0.5))
+	if err != nil {
+		t.Fatalf("SegmentParagraphs failed: %v", err)
+	}
+	for _, para := range paragraphs {
+		if para.Text != text[para.Start:para.End] {
+			t.Errorf("paragraph %+v does not match its offsets", para)
+		}
+		for _, sent := range para.Sentences {
+			if sent.Text != sent.Trimmed || sent.Text == "" {
+				t.Errorf("sentence %q not stripped", sent.Text)
+			}
+		}
+	}
+	if n := len(paragraphs); n == 0 || paragraphs[n-1].Text != "Two." {
+		t.Errorf("paragraphs = %+v, want the last to be %q", paragraphs, "Two.")
+	}
+}
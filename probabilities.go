@@ -0,0 +1,127 @@
+package sat
+
+import (
+	"context"
+	"unicode/utf8"
+
+	"github.com/jamesainslie/go-sat/tokenizer"
+)
+
+// TokenProbability is the boundary probability predicted for a single token.
+type TokenProbability struct {
+	// Piece is the SentencePiece token text (with ▁ marking word starts).
+	Piece string
+
+	// Start and End are byte offsets of the token in the input text.
+	// Tokens consisting only of ▁ have Start == End.
+	Start int
+	End   int
+
+	// Probability is the probability that a sentence ends after this token.
+	Probability float32
+
+	// Labels holds the probability of every label the model predicts for
+	// this token, indexed by label column. Probability equals the entry of
+	// the sentence label (see WithSentenceLabel).
+	Labels []float32
+}
+
+// Probabilities holds the raw per-token boundary probabilities for a text.
+type Probabilities struct {
+	Text   string
+	Tokens []TokenProbability
+}
+
+// PerByte projects token probabilities onto the bytes of Text. Each token's
+// probability is placed on its last byte; all other bytes are 0. This mirrors
+// the per-character output of wtpsplit's predict_proba.
+//
+// A zero-width token, such as a lone "▁" before punctuation, covers no byte
+// of its own, so its probability is folded into the character that follows
+// it. Where two tokens meet on one byte the higher probability is kept.
+func (p Probabilities) PerByte() []float32 {
+	probs := make([]float32, len(p.Text))
+	for _, tok := range p.Tokens {
+		end := tok.End
+		if tok.Start == tok.End && tok.Start < len(p.Text) {
+			_, size := utf8.DecodeRuneInString(p.Text[tok.Start:])
+			end = tok.Start + size
+		}
+		if end > tok.Start && end <= len(probs) {
+			probs[end-1] = max(probs[end-1], tok.Probability)
+		}
+	}
+	return probs
+}
+
+// PerRune projects token probabilities onto the runes of Text. Each token's
+// probability is placed on its last rune; all other runes are 0.
+func (p Probabilities) PerRune() []float32 {
+	byteProbs := p.PerByte()
+	probs := make([]float32, 0, utf8.RuneCountInString(p.Text))
+	for i := 0; i < len(p.Text); {
+		_, size := utf8.DecodeRuneInString(p.Text[i:])
+		// A token always ends on a rune boundary, so the last byte of the
+		// rune carries the probability if any token ends here.
+		probs = append(probs, byteProbs[i+size-1])
+		i += size
+	}
+	return probs
+}
+
+// Probabilities returns the boundary probability of every token in text,
+// without applying a threshold. Empty text, or text that is entirely
+// whitespace, returns Probabilities with no tokens.
+func (s *Segmenter) Probabilities(ctx context.Context, text string) (Probabilities, error) {
+	result := Probabilities{Text: text}
+
+	tokens, labelProbs, err := s.tokenLabelProbabilities(ctx, text)
+	if err != nil {
+		return Probabilities{}, err
+	}
+
+	result.Tokens = make([]TokenProbability, len(tokens))
+	for i, tok := range tokens {
+		labels := labelProbs[i*s.numLabels : (i+1)*s.numLabels : (i+1)*s.numLabels]
+		result.Tokens[i] = TokenProbability{
+			Piece:       tok.Text,
+			Start:       tok.Start,
+			End:         tok.End,
+			Probability: labels[s.label],
+			Labels:      labels,
+		}
+	}
+	return result, nil
+}
+
+// tokenProbabilities tokenizes text and returns the tokens together with their
+// boundary probabilities. It returns nil slices if text has no tokens.
+func (s *Segmenter) tokenProbabilities(ctx context.Context, text string) ([]tokenizer.TokenInfo, []float32, error) {
+	tokens, labelProbs, err := s.tokenLabelProbabilities(ctx, text)
+	if err != nil || len(tokens) == 0 {
+		return nil, nil, err
+	}
+	return tokens, labelColumn(labelProbs, s.numLabels, s.label), nil
+}
+
+// tokenLabelProbabilities is like tokenProbabilities but returns the
+// probabilities of every label, s.numLabels values per token.
+func (s *Segmenter) tokenLabelProbabilities(ctx context.Context, text string) ([]tokenizer.TokenInfo, []float32, error) {
+	if text == "" {
+		return nil, nil, nil
+	}
+
+	// Tokenize
+	tokens := s.tokenizer.Encode(text)
+	if len(tokens) == 0 {
+		return nil, nil, nil
+	}
+
+	// Get logits for all tokens, handling chunking if needed
+	logits, err := s.getLogits(ctx, text, tokens)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tokens, sigmoidAll(logits), nil
+}
@@ -0,0 +1,73 @@
+package sat
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestProbabilities_PerByte(t *testing.T) {
+	p := Probabilities{
+		Text: "Hé. Yo",
+		Tokens: []TokenProbability{
+			{Piece: "▁H", Start: 0, End: 1, Probability: 0.1},
+			{Piece: "é", Start: 1, End: 3, Probability: 0.2},
+			{Piece: ".", Start: 3, End: 4, Probability: 0.9},
+			{Piece: "▁", Start: 5, End: 5, Probability: 0.5},
+			{Piece: "Yo", Start: 5, End: 7, Probability: 0.3},
+		},
+	}
+
+	// The zero-width "▁" is folded into the Y that follows it
+	want := []float32{0.1, 0, 0.2, 0.9, 0, 0.5, 0.3}
+	if got := p.PerByte(); !slices.Equal(got, want) {
+		t.Errorf("PerByte() = %v, want %v", got, want)
+	}
+}
+
+func TestProbabilities_ZeroWidthTokens(t *testing.T) {
+	p := Probabilities{
+		Text: "a 「日」",
+		Tokens: []TokenProbability{
+			{Piece: "▁a", Start: 0, End: 1, Probability: 0.1},
+			{Piece: "▁", Start: 2, End: 2, Probability: 0.6},
+			{Piece: "「", Start: 2, End: 5, Probability: 0.2},
+			{Piece: "日", Start: 5, End: 8, Probability: 0.3},
+			{Piece: "」", Start: 8, End: 11, Probability: 0.4},
+			{Piece: "▁", Start: 11, End: 11, Probability: 0.9}, // at the end of Text
+		},
+	}
+
+	wantBytes := []float32{0.1, 0, 0, 0, 0.6, 0, 0, 0.3, 0, 0, 0.4}
+	if got := p.PerByte(); !slices.Equal(got, wantBytes) {
+		t.Errorf("PerByte() = %v, want %v", got, wantBytes)
+	}
+	wantRunes := []float32{0.1, 0, 0.6, 0.3, 0.4}
+	if got := p.PerRune(); !slices.Equal(got, wantRunes) {
+		t.Errorf("PerRune() = %v, want %v", got, wantRunes)
+	}
+}
+
+func TestProbabilities_PerRune(t *testing.T) {
+	p := Probabilities{
+		Text: "日本。",
+		Tokens: []TokenProbability{
+			{Piece: "▁日本", Start: 0, End: 6, Probability: 0.2},
+			{Piece: "。", Start: 6, End: 9, Probability: 0.95},
+		},
+	}
+
+	want := []float32{0, 0.2, 0.95}
+	if got := p.PerRune(); !slices.Equal(got, want) {
+		t.Errorf("PerRune() = %v, want %v", got, want)
+	}
+}
+
+func TestProbabilities_Empty(t *testing.T) {
+	var p Probabilities
+	if got := p.PerByte(); len(got) != 0 {
+		t.Errorf("PerByte() = %v, want empty", got)
+	}
+	if got := p.PerRune(); len(got) != 0 {
+		t.Errorf("PerRune() = %v, want empty", got)
+	}
+}
@@ -2,34 +2,44 @@ package sat
 
 import (
 	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log/slog"
 	"math"
 	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
 
 	"github.com/jamesainslie/go-sat/inference"
 	"github.com/jamesainslie/go-sat/tokenizer"
 )
 
-const (
-	// maxSeqLen is the maximum sequence length supported by the model.
-	// The model supports positions 0-513, so max is 514 tokens.
-	// We use 512 to leave margin for safety.
-	maxSeqLen = 512
-
-	// chunkOverlap is the number of overlapping tokens between chunks.
-	// This ensures boundary detection works properly at chunk boundaries.
-	chunkOverlap = 64
-)
-
 // Segmenter detects sentence boundaries using wtpsplit/SaT ONNX models.
 // It is safe for concurrent use.
 type Segmenter struct {
-	tokenizer *tokenizer.Tokenizer
-	pool      *inference.Pool
-	threshold float32
-	logger    *slog.Logger
+	tokenizer          *tokenizer.Tokenizer
+	pool               *inference.Pool
+	paragraphThreshold float32
+	batchSize          int
+	parallelism        int // pooled sessions one call may use at once
+	chunkSize          int // text tokens per chunk
+	chunkStride        int // tokens between chunk starts
+	stitching          Stitching
+	specialTokens      bool          // frame chunks with <s> and </s>
+	numLabels          int           // label columns predicted per token
+	label              int           // label column marking sentence boundaries
+	newlineLabel       int           // label column predicting newlines, or -1
+	defaults           callConfig    // per-call settings unless overridden
+	languages          languageRules // per-language call options
+	cache              *LogitCache
+	cacheNS            [sha256.Size]byte // separates this model's cache entries
+	logger             *slog.Logger
 }
 
 // New creates a Segmenter with the specified model files.
@@ -39,6 +49,22 @@ func New(modelPath, tokenizerPath string, opts ...Option) (*Segmenter, error) {
 		opt(&cfg)
 	}
 
+	src := modelSource{path: modelPath}
+	if cfg.adapter != "" {
+		if isAdapterWeights(cfg.adapter) {
+			configPath := filepath.Join(filepath.Dir(cfg.adapter), inference.AdapterConfigFile)
+			if err := loadAdapter(&cfg, &src, cfg.adapter, configPath, os.ReadFile); err != nil {
+				return nil, err
+			}
+		} else {
+			if cfg.preoptimized() {
+				return nil, fmt.Errorf("%w: model variant %q cannot be combined with a preoptimized model", ErrInvalidAdapter, cfg.adapter)
+			}
+			modelPath = variantPath(modelPath, cfg.adapter)
+			src.path = modelPath
+		}
+	}
+
 	// Check model file exists
 	if _, err := os.Stat(modelPath); err != nil {
 		if errors.Is(err, os.ErrNotExist) {
@@ -56,23 +82,305 @@ func New(modelPath, tokenizerPath string, opts ...Option) (*Segmenter, error) {
 		return nil, fmt.Errorf("%w: %w", ErrTokenizerFailed, err)
 	}
 
-	// Create session pool
-	pool, err := inference.NewPool(modelPath, cfg.poolSize)
+	if cfg.cache != nil {
+		src.tokenizerID = fileIdentity(tokenizerPath)
+	}
+	return newWithModel(src, tok, cfg)
+}
+
+// NewFromBytes creates a Segmenter from the contents of the model and
+// tokenizer files, such as ones embedded with go:embed. With the native
+// backend the model data is retained and must not be modified.
+func NewFromBytes(model, tokenizerModel []byte, opts ...Option) (*Segmenter, error) {
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var src modelSource
+	if cfg.adapter != "" {
+		if !isAdapterWeights(cfg.adapter) {
+			return nil, fmt.Errorf("%w: model variant %q needs a model path", ErrInvalidAdapter, cfg.adapter)
+		}
+		configPath := filepath.Join(filepath.Dir(cfg.adapter), inference.AdapterConfigFile)
+		if err := loadAdapter(&cfg, &src, cfg.adapter, configPath, os.ReadFile); err != nil {
+			return nil, err
+		}
+	}
+	return newFromBytes(model, tokenizerModel, src, cfg)
+}
+
+// newFromBytes implements NewFromBytes once any adapter has been loaded
+// into cfg and src.
+func newFromBytes(model, tokenizerModel []byte, src modelSource, cfg config) (*Segmenter, error) {
+	if len(model) == 0 {
+		return nil, fmt.Errorf("%w: empty model data", ErrInvalidModel)
+	}
+	if cfg.preoptimized() {
+		// The preoptimized file would silently replace the model data
+		return nil, fmt.Errorf("%w: a preoptimized model needs the model to be loaded from a path", ErrInvalidModel)
+	}
+
+	tok, err := tokenizer.NewFromBytes(tokenizerModel)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTokenizerFailed, err)
+	}
+
+	src.data = model
+	if cfg.cache != nil {
+		src.tokenizerID = dataIdentity(tokenizerModel)
+	}
+	return newWithModel(src, tok, cfg)
+}
+
+// NewFromReader creates a Segmenter from model and tokenizer files read in
+// full from the given readers.
+func NewFromReader(model, tokenizerModel io.Reader, opts ...Option) (*Segmenter, error) {
+	modelData, err := io.ReadAll(model)
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading model: %w", ErrInvalidModel, err)
+	}
+	tokenizerData, err := io.ReadAll(tokenizerModel)
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading tokenizer: %w", ErrTokenizerFailed, err)
+	}
+
+	return NewFromBytes(modelData, tokenizerData, opts...)
+}
+
+// NewFromFS creates a Segmenter from model and tokenizer files in fsys. An
+// adapter set with WithAdapter is also read from fsys.
+func NewFromFS(fsys fs.FS, modelPath, tokenizerPath string, opts ...Option) (*Segmenter, error) {
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var src modelSource
+	if cfg.adapter != "" {
+		if isAdapterWeights(cfg.adapter) {
+			configPath := path.Join(path.Dir(cfg.adapter), inference.AdapterConfigFile)
+			readFile := func(name string) ([]byte, error) { return fs.ReadFile(fsys, name) }
+			if err := loadAdapter(&cfg, &src, cfg.adapter, configPath, readFile); err != nil {
+				return nil, err
+			}
+		} else {
+			modelPath = variantPath(modelPath, cfg.adapter)
+		}
+	}
+
+	modelData, err := fs.ReadFile(fsys, modelPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrModelNotFound, modelPath)
+		}
+		return nil, fmt.Errorf("reading model file: %w", err)
+	}
+	tokenizerData, err := fs.ReadFile(fsys, tokenizerPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrTokenizerFailed, tokenizerPath)
+		}
+		return nil, fmt.Errorf("%w: %w", ErrTokenizerFailed, err)
+	}
+
+	return newFromBytes(modelData, tokenizerData, src, cfg)
+}
+
+// newWithModel creates the inference pool for src and assembles a Segmenter
+// around it. tok is closed if the pool cannot be created.
+func newWithModel(src modelSource, tok *tokenizer.Tokenizer, cfg config) (*Segmenter, error) {
+	pool, err := newPool(src, cfg)
 	if err != nil {
 		_ = tok.Close()
 		return nil, fmt.Errorf("%w: %w", ErrInvalidModel, err)
 	}
 
-	return &Segmenter{
-		tokenizer: tok,
-		pool:      pool,
-		threshold: cfg.threshold,
-		logger:    cfg.logger,
-	}, nil
+	s, err := newSegmenter(tok, pool, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if s.cache != nil {
+		s.cacheNS = s.cacheNamespace(src.identity(cfg))
+	}
+	return s, nil
+}
+
+// NewWithBackend creates a Segmenter that tokenizes with tok and runs
+// inference with backend, for plugging in another runtime or a test fake
+// (see package sattest). Since a Backend need not be safe for concurrent use,
+// calls to it are serialized; WithPoolSize has no effect. NewWithBackend
+// takes ownership of backend and tok: the Segmenter closes them in Close, and
+// they are closed before NewWithBackend returns an error.
+func NewWithBackend(backend inference.Backend, tok *tokenizer.Tokenizer, opts ...Option) (*Segmenter, error) {
+	// closeAll releases the arguments when construction fails
+	closeAll := func() {
+		if backend != nil {
+			_ = backend.Close()
+		}
+		if tok != nil {
+			_ = tok.Close()
+		}
+	}
+
+	if backend == nil {
+		closeAll()
+		return nil, fmt.Errorf("%w: nil backend", ErrInvalidModel)
+	}
+	if tok == nil {
+		closeAll()
+		return nil, fmt.Errorf("%w: nil tokenizer", ErrTokenizerFailed)
+	}
+	if n := backend.MaxSequenceLength(); n <= 0 {
+		closeAll()
+		return nil, fmt.Errorf("%w: maximum sequence length %d", ErrInvalidModel, n)
+	}
+
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	pool, err := inference.NewPoolFunc(1, func() (inference.Backend, error) {
+		return backend, nil
+	})
+	if err != nil {
+		closeAll()
+		return nil, fmt.Errorf("%w: %w", ErrInvalidModel, err)
+	}
+
+	return newSegmenter(tok, pool, cfg)
+}
+
+// segmenterIDs numbers Segmenters whose model has no identity of its own.
+var segmenterIDs atomic.Int64
+
+// newSegmenter assembles a Segmenter from its components. If the
+// configuration does not fit the model, tok and pool are closed.
+func newSegmenter(tok *tokenizer.Tokenizer, pool *inference.Pool, cfg config) (*Segmenter, error) {
+	chunkSize, chunkStride := chunkLayout(pool.MaxSequenceLength(), cfg)
+	s := &Segmenter{
+		tokenizer:          tok,
+		pool:               pool,
+		paragraphThreshold: cfg.paragraphThreshold,
+		batchSize:          cfg.batchSize,
+		parallelism:        cfg.parallelism,
+		chunkSize:          chunkSize,
+		chunkStride:        chunkStride,
+		stitching:          cfg.stitching,
+		specialTokens:      cfg.specialTokens,
+		numLabels:          pool.NumLabels(),
+		label:              cfg.label,
+		newlineLabel:       cfg.newlineLabel,
+		languages:          cfg.languages,
+		cache:              cfg.cache,
+		logger:             cfg.logger,
+		defaults: callConfig{
+			threshold:            cfg.threshold,
+			adaptive:             cfg.adaptive,
+			lengths:              cfg.lengths,
+			stripWhitespace:      cfg.stripWhitespace,
+			dropEmpty:            cfg.dropEmpty,
+			attachClosing:        cfg.attachClosing,
+			skipWhitespaceTokens: cfg.skipWhitespace,
+			detectLanguage:       cfg.detectLanguage,
+		},
+	}
+	// Models without a known identity never share cache entries
+	s.cacheNS = s.cacheNamespace(fmt.Sprintf("segmenter:%d", segmenterIDs.Add(1)))
+	if s.label < 0 || s.label >= s.numLabels {
+		_ = s.Close()
+		return nil, fmt.Errorf("%w: sentence label %d, model predicts %d labels", ErrInvalidLabel, s.label, s.numLabels)
+	}
+	if s.newlineLabel >= s.numLabels {
+		_ = s.Close()
+		return nil, fmt.Errorf("%w: newline label %d, model predicts %d labels", ErrInvalidLabel, s.newlineLabel, s.numLabels)
+	}
+	if err := s.defaults.lengths.validate(); err != nil {
+		_ = s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// modelSource locates the segmentation model, either as a file path or as
+// the file's contents when data is non-nil.
+type modelSource struct {
+	path string
+	data []byte
+
+	// tokenizerID identifies the paired tokenizer; it is only set when a
+	// logit cache needs it.
+	tokenizerID string
+
+	// adapterID identifies LoRA weights merged into the model, if any.
+	adapterID string
+}
+
+// identity names the model, its tokenizer and the backend running it, to
+// separate their entries in a shared logit cache. Every backend setting that
+// can change the logits is part of it.
+func (src modelSource) identity(cfg config) string {
+	model := fileIdentity(src.path)
+	if src.data != nil {
+		model = dataIdentity(src.data)
+	}
+	return fmt.Sprintf("%s\x00%s\x00%s\x00%s", model, src.tokenizerID, backendIdentity(cfg), src.adapterID)
+}
+
+// backendIdentity describes the backend settings of cfg that affect the
+// logits. Thread counts and the execution mode only change how the work is
+// scheduled, so they are left out.
+func backendIdentity(cfg config) string {
+	if native := cfg.native; native != nil {
+		return fmt.Sprintf("native:heads=%d:eps=%g:lookahead=%d",
+			native.NumHeads, native.LayerNormEps, native.Lookahead)
+	}
+	// A preoptimized model replaces the model when it exists
+	optimized := ""
+	if cfg.session.PreoptimizedModelPath != "" {
+		optimized = fileIdentity(cfg.session.PreoptimizedModelPath)
+	}
+	return fmt.Sprintf("onnx:opt=%d:%s", cfg.session.GraphOptimization, optimized)
+}
+
+// newPool creates the inference pool selected by cfg.
+func newPool(src modelSource, cfg config) (*inference.Pool, error) {
+	if cfg.native == nil {
+		if src.data != nil {
+			return inference.NewPoolFromBytesWithConfig(src.data, cfg.poolSize, cfg.session)
+		}
+		return inference.NewPoolWithConfig(src.path, cfg.poolSize, cfg.session)
+	}
+
+	// A native model is immutable, so every pool slot shares the same weights
+	var (
+		model *inference.NativeModel
+		err   error
+	)
+	if src.data != nil {
+		model, err = inference.LoadNativeModelFromBytes(src.data, *cfg.native)
+	} else {
+		model, err = inference.LoadNativeModel(src.path, *cfg.native)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return inference.NewPoolFunc(cfg.poolSize, func() (inference.Backend, error) {
+		return model, nil
+	})
 }
 
 // IsComplete returns whether text appears to be a complete sentence.
-func (s *Segmenter) IsComplete(ctx context.Context, text string) (complete bool, confidence float32, err error) {
+// CallThreshold, or a threshold registered for the language of text with
+// WithLanguageOptions, overrides the threshold; other call options are
+// ignored.
+func (s *Segmenter) IsComplete(ctx context.Context, text string, opts ...CallOption) (complete bool, confidence float32, err error) {
+	cc, err := s.textConfig(text, opts)
+	if err != nil {
+		return false, 0, err
+	}
+
 	if text == "" {
 		return false, 0.0, nil
 	}
@@ -84,179 +392,301 @@ func (s *Segmenter) IsComplete(ctx context.Context, text string) (complete bool,
 	}
 
 	// Get logits for all tokens, handling chunking if needed
-	logits, err := s.getLogits(ctx, tokens)
+	logits, err := s.getLogits(ctx, text, tokens)
 	if err != nil {
 		return false, 0, err
 	}
 
 	// Check last token's boundary probability
-	lastLogit := logits[len(logits)-1]
+	lastLogit := logits[(len(tokens)-1)*s.numLabels+s.label]
 	prob := sigmoid(lastLogit)
 
-	complete = prob > s.threshold
+	complete = prob > cc.threshold
 	return complete, prob, nil
 }
 
 // Segment splits text into sentences.
-func (s *Segmenter) Segment(ctx context.Context, text string) ([]string, error) {
-	if text == "" {
-		return nil, nil
+// Unless text is entirely whitespace, or whitespace is stripped or dropped,
+// concatenating the returned sentences reproduces text exactly. Call options
+// override the Segmenter's threshold, length limits and whitespace handling
+// for this call.
+func (s *Segmenter) Segment(ctx context.Context, text string, opts ...CallOption) ([]string, error) {
+	cc, err := s.textConfig(text, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	// Tokenize
-	tokens := s.tokenizer.Encode(text)
-	if len(tokens) == 0 {
-		return nil, nil
+	detailed, err := s.segmentDetailed(ctx, text, cc)
+	if err != nil || len(detailed) == 0 {
+		return nil, err
 	}
 
-	// Get logits for all tokens, handling chunking if needed
-	logits, err := s.getLogits(ctx, tokens)
+	return sentenceTexts(detailed), nil
+}
+
+// SegmentWithBoundaries splits text into sentences and returns boundary positions.
+// Boundaries are byte offsets where each sentence ends in the original text,
+// so sentences[i] == text[boundaries[i-1]:boundaries[i]]. With stripped
+// whitespace each boundary is the end of the trimmed sentence, and dropped
+// sentences have no boundary.
+func (s *Segmenter) SegmentWithBoundaries(ctx context.Context, text string, opts ...CallOption) (sentences []string, boundaries []int, err error) {
+	cc, err := s.textConfig(text, opts)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	// Find boundaries using token byte offsets
-	var boundaries []int
-	for i, logit := range logits {
-		if sigmoid(logit) > s.threshold {
-			// Map token end position to character position
-			if i < len(tokens) {
-				boundaries = append(boundaries, tokens[i].End)
-			}
-		}
+	detailed, err := s.segmentDetailed(ctx, text, cc)
+	if err != nil || len(detailed) == 0 {
+		return nil, nil, err
 	}
 
-	// Split text at boundaries
-	if len(boundaries) == 0 {
-		return []string{text}, nil
+	boundaries = make([]int, len(detailed))
+	for i, sent := range detailed {
+		boundaries[i] = sent.End
 	}
+	return sentenceTexts(detailed), boundaries, nil
+}
 
-	var sentences []string
-	start := 0
-	for _, end := range boundaries {
-		if end > start && end <= len(text) {
-			sentences = append(sentences, text[start:end])
-			start = end
-		}
+// SegmentDetailed splits text into sentences and returns each one with its
+// byte and rune offsets, token range and the boundary probability that ended
+// it. Empty text, or text that is entirely whitespace, returns nil. The
+// whitespace and punctuation options, such as WithStripWhitespace, apply.
+func (s *Segmenter) SegmentDetailed(ctx context.Context, text string, opts ...CallOption) ([]Sentence, error) {
+	cc, err := s.textConfig(text, opts)
+	if err != nil {
+		return nil, err
 	}
-	if start < len(text) {
-		sentences = append(sentences, text[start:])
+	return s.segmentDetailed(ctx, text, cc)
+}
+
+// segmentDetailed implements SegmentDetailed with the settings of one call.
+func (s *Segmenter) segmentDetailed(ctx context.Context, text string, cc callConfig) ([]Sentence, error) {
+	tokens, probs, err := s.tokenProbabilities(ctx, text)
+	if err != nil || len(tokens) == 0 {
+		return nil, err
 	}
 
-	return sentences, nil
+	return cc.sentences(text, tokens, probs, cc.documentSplits(text, tokens, probs)), nil
 }
 
-// SegmentWithBoundaries splits text into sentences and returns boundary positions.
-// Boundaries are character offsets where each sentence ends in the original text.
-func (s *Segmenter) SegmentWithBoundaries(ctx context.Context, text string) (sentences []string, boundaries []int, err error) {
-	if text == "" {
-		return nil, nil, nil
+// SegmentByLabel splits text after every token whose probability for the
+// given label column exceeds threshold. With multi-label models this segments
+// on auxiliary heads, e.g. predicted newlines. Label 0 with the Segmenter's
+// threshold is equivalent to SegmentDetailed for single-label models. Length
+// limits apply; threshold takes the place of the threshold call options.
+func (s *Segmenter) SegmentByLabel(ctx context.Context, text string, label int, threshold float32, opts ...CallOption) ([]Sentence, error) {
+	if label < 0 || label >= s.numLabels {
+		return nil, fmt.Errorf("%w: label %d, model predicts %d labels", ErrInvalidLabel, label, s.numLabels)
+	}
+	cc, err := s.textConfig(text, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	// Tokenize
-	tokens := s.tokenizer.Encode(text)
-	if len(tokens) == 0 {
-		return nil, nil, nil
+	tokens, labelProbs, err := s.tokenLabelProbabilities(ctx, text)
+	if err != nil || len(tokens) == 0 {
+		return nil, err
 	}
 
-	// Get logits for all tokens, handling chunking if needed
-	logits, err := s.getLogits(ctx, tokens)
+	probs := labelColumn(labelProbs, s.numLabels, label)
+	return cc.sentences(text, tokens, probs, cc.sentenceSplits(text, tokens, probs, threshold)), nil
+}
+
+// NumLabels returns the number of label columns the model predicts per token.
+// Their probabilities are available through Probabilities.
+func (s *Segmenter) NumLabels() int {
+	return s.numLabels
+}
+
+// SegmentBatch splits each of texts into sentences. The texts (and the chunks
+// of long texts) are packed into batched model runs, which is much faster
+// than calling Segment for each of many short texts. The result has one entry
+// per input, as Segment would return it with the same call options.
+func (s *Segmenter) SegmentBatch(ctx context.Context, texts []string, opts ...CallOption) ([][]string, error) {
+	if _, err := s.callConfig(opts); err != nil {
+		return nil, err
+	}
+
+	seqs := make([][]tokenizer.TokenInfo, len(texts))
+	for i, text := range texts {
+		seqs[i] = s.tokenizer.Encode(text)
+	}
+
+	logits, err := s.textLogits(ctx, texts, seqs)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	// Find boundaries using token byte offsets
-	for i, logit := range logits {
-		if sigmoid(logit) > s.threshold {
-			if i < len(tokens) {
-				boundaries = append(boundaries, tokens[i].End)
-			}
+	results := make([][]string, len(texts))
+	for i, text := range texts {
+		if len(seqs[i]) == 0 {
+			continue
+		}
+		cc, err := s.textConfig(text, opts)
+		if err != nil {
+			return nil, err
 		}
+		probs := sigmoidAll(labelColumn(logits[i], s.numLabels, s.label))
+		sentences := cc.sentences(text, seqs[i], probs, cc.documentSplits(text, seqs[i], probs))
+		results[i] = sentenceTexts(sentences)
 	}
+	return results, nil
+}
 
-	// Split text at boundaries
-	if len(boundaries) == 0 {
-		return []string{text}, []int{len(text)}, nil
+// getLogits returns the logits of every label for tokens, the tokens of
+// text, chunking if necessary and consulting the logit cache if there is
+// one. Label k of token t is at index t*s.numLabels+k.
+func (s *Segmenter) getLogits(ctx context.Context, text string, tokens []tokenizer.TokenInfo) ([]float32, error) {
+	logits, err := s.textLogits(ctx, []string{text}, [][]tokenizer.TokenInfo{tokens})
+	if err != nil {
+		return nil, err
 	}
+	return logits[0], nil
+}
 
-	start := 0
-	for _, end := range boundaries {
-		if end > start && end <= len(text) {
-			sentences = append(sentences, text[start:end])
-			start = end
+// getLogitsBatch returns the logits of every label for several token
+// sequences, laid out as by getLogits. Sequences longer than the chunk size
+// are split into overlapping chunks, the chunks of all sequences are packed
+// into batches of up to batchSize rows, and each batch runs as a single
+// inference call. Overlapping predictions are combined according to the
+// stitching strategy.
+func (s *Segmenter) getLogitsBatch(ctx context.Context, seqs [][]tokenizer.TokenInfo) ([][]float32, error) {
+	var chunks []chunk
+	lengths := make([]int, len(seqs))
+	for i, tokens := range seqs {
+		lengths[i] = len(tokens)
+		for _, r := range chunkRanges(len(tokens), s.chunkSize, s.chunkStride) {
+			chunks = append(chunks, chunk{seq: i, start: r[0], end: r[1]})
 		}
 	}
-	if start < len(text) {
-		sentences = append(sentences, text[start:])
-		boundaries = append(boundaries, len(text))
-	}
 
-	return sentences, boundaries, nil
-}
+	// Group chunks of similar length so batches need little padding
+	sort.SliceStable(chunks, func(a, b int) bool {
+		return chunks[a].end-chunks[a].start < chunks[b].end-chunks[b].start
+	})
 
-// getLogits returns logits for all tokens, chunking if necessary.
-func (s *Segmenter) getLogits(ctx context.Context, tokens []tokenizer.TokenInfo) ([]float32, error) {
-	// Acquire session from pool
-	session, err := s.pool.Acquire(ctx)
+	labels := s.numLabels
+	st := newStitcher(s.stitching, labels, lengths)
+
+	var batches [][]chunk
+	for b := 0; b < len(chunks); b += s.batchSize {
+		batches = append(batches, chunks[b:min(b+s.batchSize, len(chunks))])
+	}
+
+	results, err := s.inferBatches(ctx, seqs, batches)
 	if err != nil {
 		return nil, err
 	}
-	defer s.pool.Release(session)
 
-	// If sequence fits in one chunk, process directly
-	if len(tokens) <= maxSeqLen {
-		return s.inferChunk(ctx, session, tokens)
+	// Stitch in batch order so the result does not depend on scheduling
+	for b, batch := range batches {
+		for i, c := range batch {
+			logits := results[b][i]
+			if s.specialTokens {
+				// Drop the predictions for <s> and </s>
+				logits = logits[labels : len(logits)-labels]
+			}
+			st.add(c, logits)
+		}
 	}
 
-	// Process in overlapping chunks
-	logits := make([]float32, len(tokens))
-	counts := make([]int, len(tokens)) // Track how many times each position was processed
+	return st.result(), nil
+}
 
-	stride := maxSeqLen - chunkOverlap
-	for start := 0; start < len(tokens); start += stride {
-		end := start + maxSeqLen
-		if end > len(tokens) {
-			end = len(tokens)
-		}
+// inferBatches runs every batch of chunks and returns the logits of each, in
+// batch order. Batches are shared among up to parallelism workers, each
+// holding its own pooled session; the first error cancels the others.
+func (s *Segmenter) inferBatches(ctx context.Context, seqs [][]tokenizer.TokenInfo, batches [][]chunk) ([][][]float32, error) {
+	results := make([][][]float32, len(batches))
+	workers := min(s.parallelism, s.pool.Size(), len(batches))
+	if workers == 0 {
+		return results, nil
+	}
 
-		chunk := tokens[start:end]
-		chunkLogits, err := s.inferChunk(ctx, session, chunk)
-		if err != nil {
-			return nil, err
-		}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		next     atomic.Int64
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
 
-		// Accumulate logits (for averaging in overlap regions)
-		for i, logit := range chunkLogits {
-			logits[start+i] += logit
-			counts[start+i]++
-		}
+	padID := int64(s.tokenizer.PadID())
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// Acquire session from pool
+			session, err := s.pool.Acquire(ctx)
+			if err != nil {
+				fail(err)
+				return
+			}
+			defer s.pool.Release(session)
+
+			for {
+				b := int(next.Add(1) - 1)
+				if b >= len(batches) || ctx.Err() != nil {
+					return
+				}
+
+				inputIDs := make([][]int64, len(batches[b]))
+				for i, c := range batches[b] {
+					inputIDs[i] = s.chunkIDs(seqs[c.seq][c.start:c.end])
+				}
+
+				logits, err := inference.InferBatchLabels(ctx, session, inputIDs, padID)
+				if err != nil {
+					fail(err)
+					return
+				}
+				results[b] = logits
+			}
+		}()
+	}
+	wg.Wait()
 
-		// Stop if we've reached the end
-		if end >= len(tokens) {
-			break
-		}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	// Workers stop early, without an error of their own, if ctx is canceled
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
+	return results, nil
+}
 
-	// Average logits in overlapping regions
-	for i := range logits {
-		if counts[i] > 1 {
-			logits[i] /= float32(counts[i])
-		}
+// chunkIDs returns the model input IDs for a chunk of tokens, framed with
+// <s> and </s> unless special tokens are disabled.
+func (s *Segmenter) chunkIDs(tokens []tokenizer.TokenInfo) []int64 {
+	if !s.specialTokens {
+		return tokenIDs(tokens)
 	}
 
-	return logits, nil
+	ids := make([]int64, 0, len(tokens)+numSpecialTokens)
+	ids = append(ids, int64(s.tokenizer.BOSID()))
+	for _, t := range tokens {
+		ids = append(ids, int64(t.ID))
+	}
+	return append(ids, int64(s.tokenizer.EOSID()))
 }
 
-// inferChunk runs inference on a single chunk of tokens.
-func (s *Segmenter) inferChunk(ctx context.Context, session *inference.Session, tokens []tokenizer.TokenInfo) ([]float32, error) {
-	inputIDs := make([]int64, len(tokens))
-	attentionMask := make([]int64, len(tokens))
+// tokenIDs returns the model input IDs for tokens.
+func tokenIDs(tokens []tokenizer.TokenInfo) []int64 {
+	ids := make([]int64, len(tokens))
 	for i, t := range tokens {
-		inputIDs[i] = int64(t.ID)
-		attentionMask[i] = 1
+		ids[i] = int64(t.ID)
 	}
-
-	return session.Infer(ctx, inputIDs, attentionMask)
+	return ids
 }
 
 // Close releases all resources.
@@ -284,3 +714,25 @@ func (s *Segmenter) Close() error {
 func sigmoid(x float32) float32 {
 	return float32(1.0 / (1.0 + math.Exp(float64(-x))))
 }
+
+// labelColumn returns column label of row-major per-token values with
+// numLabels values per token.
+func labelColumn(values []float32, numLabels, label int) []float32 {
+	if numLabels == 1 {
+		return values
+	}
+	column := make([]float32, len(values)/numLabels)
+	for i := range column {
+		column[i] = values[i*numLabels+label]
+	}
+	return column
+}
+
+// sigmoidAll converts logits to boundary probabilities.
+func sigmoidAll(logits []float32) []float32 {
+	probs := make([]float32, len(logits))
+	for i, logit := range logits {
+		probs[i] = sigmoid(logit)
+	}
+	return probs
+}
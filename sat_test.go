@@ -1,10 +1,24 @@
 package sat
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
+	"testing/iotest"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/jamesainslie/go-sat/inference"
+	pb "github.com/jamesainslie/go-sat/internal/proto"
+	"github.com/jamesainslie/go-sat/sattest"
 )
 
 const (
@@ -92,8 +106,53 @@ func TestNew_WithOptions(t *testing.T) {
 	}
 	defer func() { _ = seg.Close() }()
 
-	if seg.threshold != 0.5 {
-		t.Errorf("expected threshold 0.5, got %f", seg.threshold)
+	if seg.defaults.threshold != 0.5 {
+		t.Errorf("expected threshold 0.5, got %f", seg.defaults.threshold)
+	}
+}
+
+func TestSessionOptions(t *testing.T) {
+	cfg := defaultConfig()
+	for _, opt := range []Option{
+		WithIntraOpThreads(2),
+		WithInterOpThreads(1),
+		WithGraphOptimizationLevel(inference.GraphOptimizationBasic),
+		WithExecutionMode(inference.ExecutionModeParallel),
+		WithPreoptimizedModel("model.opt.onnx"),
+		WithIntraOpThreads(0), // ignored
+	} {
+		opt(&cfg)
+	}
+
+	want := inference.SessionConfig{
+		IntraOpThreads:        2,
+		InterOpThreads:        1,
+		GraphOptimization:     inference.GraphOptimizationBasic,
+		ExecutionMode:         inference.ExecutionModeParallel,
+		PreoptimizedModelPath: "model.opt.onnx",
+	}
+	if cfg.session != want {
+		t.Errorf("session config = %+v, want %+v", cfg.session, want)
+	}
+}
+
+func TestNew_WithSessionOptions(t *testing.T) {
+	skipIfNoModel(t)
+	skipIfNoTokenizer(t)
+
+	seg, err := New(testModelPath, testTokenizerPath,
+		WithPoolSize(2),
+		WithIntraOpThreads(1),
+		WithGraphOptimizationLevel(inference.GraphOptimizationExtended),
+		WithPreoptimizedModel(filepath.Join(t.TempDir(), "missing.onnx")),
+	)
+	if err != nil {
+		t.Fatalf("New() with session options failed: %v", err)
+	}
+	defer func() { _ = seg.Close() }()
+
+	if _, err := seg.Segment(context.Background(), "Hello world. How are you?"); err != nil {
+		t.Errorf("Segment failed: %v", err)
 	}
 }
 
@@ -299,3 +358,525 @@ func TestSigmoid(t *testing.T) {
 		}
 	}
 }
+
+// writeTestTokenizer writes a small SentencePiece model to a temp file so
+// tests can tokenize text without the full XLM-RoBERTa model.
+func writeTestTokenizer(t *testing.T) string {
+	t.Helper()
+
+	normal := pb.ModelProto_SentencePiece_NORMAL
+	model := &pb.ModelProto{
+		Pieces: []*pb.ModelProto_SentencePiece{
+			{Piece: proto.String("<unk>"), Type: pb.ModelProto_SentencePiece_UNKNOWN.Enum()},
+			{Piece: proto.String("<s>"), Type: pb.ModelProto_SentencePiece_CONTROL.Enum()},
+			{Piece: proto.String("</s>"), Type: pb.ModelProto_SentencePiece_CONTROL.Enum()},
+		},
+	}
+	for _, p := range []string{"▁", "▁Hello", "▁world", "▁How", "▁are", "▁you", ".", "?", "!", "é", "€", "日本", "。", "a", "b"} {
+		model.Pieces = append(model.Pieces, &pb.ModelProto_SentencePiece{
+			Piece: proto.String(p),
+			Score: proto.Float32(-1),
+			Type:  normal.Enum(),
+		})
+	}
+
+	data, err := proto.Marshal(model)
+	if err != nil {
+		t.Fatalf("marshal tokenizer model: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "tokenizer.model")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write tokenizer model: %v", err)
+	}
+	return path
+}
+
+func TestSegmenter_SegmentBatch(t *testing.T) {
+	skipIfNoModel(t)
+	skipIfNoTokenizer(t)
+
+	seg, err := New(testModelPath, testTokenizerPath)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer func() { _ = seg.Close() }()
+
+	ctx := context.Background()
+	texts := []string{"Hello world. How are you?", "", "I am fine.", "Thanks!"}
+	results, err := seg.SegmentBatch(ctx, texts)
+	if err != nil {
+		t.Fatalf("SegmentBatch failed: %v", err)
+	}
+	if len(results) != len(texts) {
+		t.Fatalf("expected %d results, got %d", len(texts), len(results))
+	}
+
+	for i, text := range texts {
+		want, err := seg.Segment(ctx, text)
+		if err != nil {
+			t.Fatalf("Segment failed: %v", err)
+		}
+		if !slices.Equal(results[i], want) {
+			t.Errorf("text %d: SegmentBatch = %q, Segment = %q", i, results[i], want)
+		}
+	}
+}
+
+func TestNew_NativeBackendInvalidModel(t *testing.T) {
+	tmpModel := filepath.Join(t.TempDir(), "model.safetensors")
+	if err := os.WriteFile(tmpModel, []byte("not a model"), 0o600); err != nil {
+		t.Fatalf("write model: %v", err)
+	}
+
+	_, err := New(tmpModel, writeTestTokenizer(t), WithNativeBackend(inference.NativeConfig{}))
+	if !errors.Is(err, ErrInvalidModel) {
+		t.Errorf("expected ErrInvalidModel, got: %v", err)
+	}
+}
+
+func TestNewWithBackend_NilArguments(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+
+	if _, err := NewWithBackend(nil, tok); !errors.Is(err, ErrInvalidModel) {
+		t.Errorf("nil backend: expected ErrInvalidModel, got: %v", err)
+	}
+	backend := sattest.NewSentenceBackend(tok)
+	if _, err := NewWithBackend(backend, nil); !errors.Is(err, ErrTokenizerFailed) {
+		t.Errorf("nil tokenizer: expected ErrTokenizerFailed, got: %v", err)
+	}
+	if !backend.Closed() {
+		t.Error("nil tokenizer: backend not closed")
+	}
+
+	backend = sattest.NewSentenceBackend(tok)
+	backend.MaxLen = 0
+	if _, err := NewWithBackend(backend, tok); !errors.Is(err, ErrInvalidModel) {
+		t.Errorf("zero sequence length: expected ErrInvalidModel, got: %v", err)
+	}
+	if !backend.Closed() {
+		t.Error("zero sequence length: backend not closed")
+	}
+}
+
+func TestNewWithBackend_InvalidLabelClosesBackend(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	backend := sattest.NewSentenceBackend(tok)
+
+	if _, err := NewWithBackend(backend, tok, WithSentenceLabel(3)); !errors.Is(err, ErrInvalidLabel) {
+		t.Errorf("expected ErrInvalidLabel, got: %v", err)
+	}
+	if !backend.Closed() {
+		t.Error("backend not closed")
+	}
+}
+
+func TestNewWithBackend_Segment(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	backend := sattest.NewSentenceBackend(tok)
+
+	seg, err := NewWithBackend(backend, tok)
+	if err != nil {
+		t.Fatalf("NewWithBackend() failed: %v", err)
+	}
+
+	ctx := context.Background()
+	got, err := seg.Segment(ctx, "Hello world. How are you? Fine")
+	if err != nil {
+		t.Fatalf("Segment failed: %v", err)
+	}
+	want := []string{"Hello world.", " How are you?", " Fine"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Segment = %q, want %q", got, want)
+	}
+
+	complete, _, err := seg.IsComplete(ctx, "Is it done?")
+	if err != nil {
+		t.Fatalf("IsComplete failed: %v", err)
+	}
+	if !complete {
+		t.Error("IsComplete = false for text ending in a boundary")
+	}
+
+	if err := seg.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if !backend.Closed() {
+		t.Error("Close did not close the backend")
+	}
+}
+
+func TestNewWithBackend_Chunking(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	text := strings.TrimSpace(strings.Repeat("One two three. Four five six! ", 20))
+
+	full := sattest.NewSentenceBackend(tok)
+	short := sattest.NewSentenceBackend(tok)
+	short.MaxLen = 16
+
+	var results [2][]string
+	for i, backend := range []*sattest.Backend{full, short} {
+		seg, err := NewWithBackend(backend, tok, WithBatchSize(4))
+		if err != nil {
+			t.Fatalf("NewWithBackend() failed: %v", err)
+		}
+		results[i], err = seg.Segment(context.Background(), text)
+		if err != nil {
+			t.Fatalf("Segment failed: %v", err)
+		}
+		_ = seg.Close()
+	}
+
+	if len(results[0]) != 40 {
+		t.Errorf("expected 40 sentences, got %d", len(results[0]))
+	}
+	if !slices.Equal(results[0], results[1]) {
+		t.Errorf("chunked result differs:\nfull:  %q\nshort: %q", results[0], results[1])
+	}
+	if full.Rows() != 1 {
+		t.Errorf("full-length backend inferred %d rows, want 1", full.Rows())
+	}
+	if short.Rows() <= 1 || short.Calls() >= short.Rows() {
+		t.Errorf("short backend inferred %d rows in %d calls, want several rows batched", short.Rows(), short.Calls())
+	}
+}
+
+// gatedBackend holds each call until gate.want calls are in flight at once,
+// or a timeout expires.
+type gatedBackend struct {
+	*sattest.Backend
+	gate *callGate
+}
+
+type callGate struct {
+	mu       sync.Mutex
+	want     int
+	inFlight int
+	peak     int
+	open     chan struct{}
+	opened   bool
+}
+
+func (b *gatedBackend) InferBatchLabels(ctx context.Context, batch [][]int64, padID int64) ([][]float32, error) {
+	g := b.gate
+	g.mu.Lock()
+	g.inFlight++
+	g.peak = max(g.peak, g.inFlight)
+	if g.inFlight == g.want && !g.opened {
+		close(g.open)
+		g.opened = true
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-g.open:
+	case <-time.After(2 * time.Second):
+	}
+
+	g.mu.Lock()
+	g.inFlight--
+	g.mu.Unlock()
+	return b.Backend.InferBatchLabels(ctx, batch, padID)
+}
+
+func TestSegment_Parallelism(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	text := strings.TrimSpace(strings.Repeat("One two three. Four five six! ", 40))
+	const workers = 4
+
+	gate := &callGate{want: workers, open: make(chan struct{})}
+	var backends []*sattest.Backend
+	pool, err := inference.NewPoolFunc(workers, func() (inference.Backend, error) {
+		b := sattest.NewSentenceBackend(tok)
+		b.MaxLen = 16
+		backends = append(backends, b)
+		return &gatedBackend{Backend: b, gate: gate}, nil
+	})
+	if err != nil {
+		t.Fatalf("NewPoolFunc() failed: %v", err)
+	}
+
+	cfg := defaultConfig()
+	WithParallelism(workers)(&cfg)
+	WithBatchSize(2)(&cfg)
+	seg, err := newSegmenter(tok, pool, cfg)
+	if err != nil {
+		t.Fatalf("newSegmenter() failed: %v", err)
+	}
+	defer func() { _ = seg.Close() }()
+
+	got, err := seg.Segment(context.Background(), text)
+	if err != nil {
+		t.Fatalf("Segment failed: %v", err)
+	}
+	if len(got) != 80 {
+		t.Errorf("expected 80 sentences, got %d", len(got))
+	}
+	if gate.peak != workers {
+		t.Errorf("peak concurrent calls = %d, want %d", gate.peak, workers)
+	}
+	for i, b := range backends {
+		if b.Calls() == 0 {
+			t.Errorf("backend %d was not used", i)
+		}
+	}
+
+	// Compare with serial inference
+	serial, err := NewWithBackend(sattest.NewSentenceBackend(tok), tok, WithBatchSize(2))
+	if err != nil {
+		t.Fatalf("NewWithBackend() failed: %v", err)
+	}
+	defer func() { _ = serial.Close() }()
+	want, err := serial.Segment(context.Background(), text)
+	if err != nil {
+		t.Fatalf("Segment failed: %v", err)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("parallel result differs:\nparallel: %q\nserial:   %q", got, want)
+	}
+}
+
+func TestSegment_ParallelismCanceled(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	text := strings.Repeat("One two three. ", 40)
+
+	pool, err := inference.NewPoolFunc(2, func() (inference.Backend, error) {
+		b := sattest.NewSentenceBackend(tok)
+		b.MaxLen = 16
+		return b, nil
+	})
+	if err != nil {
+		t.Fatalf("NewPoolFunc() failed: %v", err)
+	}
+	cfg := defaultConfig()
+	WithParallelism(8)(&cfg)
+	seg, err := newSegmenter(tok, pool, cfg)
+	if err != nil {
+		t.Fatalf("newSegmenter() failed: %v", err)
+	}
+	defer func() { _ = seg.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := seg.Segment(ctx, text); !errors.Is(err, context.Canceled) {
+		t.Errorf("Segment with canceled context error = %v, want context.Canceled", err)
+	}
+}
+
+func TestNewFromFS(t *testing.T) {
+	skipIfNoModel(t)
+	skipIfNoTokenizer(t)
+
+	fromFS, err := NewFromFS(os.DirFS("testdata"), filepath.Base(testModelPath), filepath.Base(testTokenizerPath))
+	if err != nil {
+		t.Fatalf("NewFromFS() failed: %v", err)
+	}
+	defer func() { _ = fromFS.Close() }()
+
+	fromPath, err := New(testModelPath, testTokenizerPath)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer func() { _ = fromPath.Close() }()
+
+	ctx := context.Background()
+	text := "Hello world. How are you?"
+	got, err := fromFS.Segment(ctx, text)
+	if err != nil {
+		t.Fatalf("Segment failed: %v", err)
+	}
+	want, err := fromPath.Segment(ctx, text)
+	if err != nil {
+		t.Fatalf("Segment failed: %v", err)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("NewFromFS segments = %q, New segments = %q", got, want)
+	}
+}
+
+func TestNewFromFS_NotFound(t *testing.T) {
+	tokenizerData, err := os.ReadFile(writeTestTokenizer(t))
+	if err != nil {
+		t.Fatalf("read tokenizer: %v", err)
+	}
+	fsys := fstest.MapFS{
+		"model.onnx":      {Data: []byte("model")},
+		"tokenizer.model": {Data: tokenizerData},
+	}
+
+	_, err = NewFromFS(fsys, "missing.onnx", "tokenizer.model")
+	if !errors.Is(err, ErrModelNotFound) {
+		t.Errorf("missing model: expected ErrModelNotFound, got: %v", err)
+	}
+	_, err = NewFromFS(fsys, "model.onnx", "missing.model")
+	if !errors.Is(err, ErrTokenizerFailed) {
+		t.Errorf("missing tokenizer: expected ErrTokenizerFailed, got: %v", err)
+	}
+}
+
+func TestNewFromBytes_Errors(t *testing.T) {
+	tokenizerData, err := os.ReadFile(writeTestTokenizer(t))
+	if err != nil {
+		t.Fatalf("read tokenizer: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		model     []byte
+		tokenizer []byte
+		opts      []Option
+		wantErr   error
+	}{
+		{"empty model", nil, tokenizerData, nil, ErrInvalidModel},
+		{"invalid tokenizer", []byte("model"), []byte{0xff, 0xff, 0xff}, nil, ErrTokenizerFailed},
+		{"invalid native model", []byte("not a model"), tokenizerData, []Option{WithNativeBackend(inference.NativeConfig{})}, ErrInvalidModel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFromBytes(tt.model, tt.tokenizer, tt.opts...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestWithPreoptimizedModel_Conflicts(t *testing.T) {
+	tok := writeTestTokenizer(t)
+	tokenizerData, err := os.ReadFile(tok)
+	if err != nil {
+		t.Fatalf("read tokenizer: %v", err)
+	}
+	preoptimized := WithPreoptimizedModel(filepath.Join(t.TempDir(), "model.opt.onnx"))
+	fsys := fstest.MapFS{
+		"model.onnx":      {Data: []byte("model")},
+		"tokenizer.model": {Data: tokenizerData},
+	}
+
+	tests := []struct {
+		name    string
+		newSeg  func() (*Segmenter, error)
+		wantErr error
+	}{
+		{"bytes", func() (*Segmenter, error) {
+			return NewFromBytes([]byte("model"), tokenizerData, preoptimized)
+		}, ErrInvalidModel},
+		{"reader", func() (*Segmenter, error) {
+			return NewFromReader(strings.NewReader("model"), bytes.NewReader(tokenizerData), preoptimized)
+		}, ErrInvalidModel},
+		{"fs", func() (*Segmenter, error) {
+			return NewFromFS(fsys, "model.onnx", "tokenizer.model", preoptimized)
+		}, ErrInvalidModel},
+		{"model variant", func() (*Segmenter, error) {
+			return New("model.onnx", tok, WithAdapter("legal"), preoptimized)
+		}, ErrInvalidAdapter},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.newSeg()
+			if !errors.Is(err, tt.wantErr) || !strings.Contains(err.Error(), "preoptimized") {
+				t.Errorf("expected %v for the preoptimized model, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestNewFromReader_ReadError(t *testing.T) {
+	readErr := errors.New("read failed")
+
+	_, err := NewFromReader(iotest.ErrReader(readErr), strings.NewReader(""))
+	if !errors.Is(err, ErrInvalidModel) || !errors.Is(err, readErr) {
+		t.Errorf("model read error: expected ErrInvalidModel wrapping %v, got: %v", readErr, err)
+	}
+	_, err = NewFromReader(strings.NewReader("model"), iotest.ErrReader(readErr))
+	if !errors.Is(err, ErrTokenizerFailed) || !errors.Is(err, readErr) {
+		t.Errorf("tokenizer read error: expected ErrTokenizerFailed wrapping %v, got: %v", readErr, err)
+	}
+}
+
+func TestNewWithBackend_MultiLabel(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	newBackend := func(maxLen int) *sattest.Backend {
+		// Label 0 fires on '.', label 1 on '!'
+		b := sattest.NewBackend(int64(tok.EncodeIDs("a.")[1]))
+		b.AuxLabels = []map[int64]bool{{int64(tok.EncodeIDs("a!")[1]): true}}
+		b.MaxLen = maxLen
+		return b
+	}
+
+	ctx := context.Background()
+	text := "One. Two! Three. Four! Five"
+
+	seg, err := NewWithBackend(newBackend(512), tok)
+	if err != nil {
+		t.Fatalf("NewWithBackend() failed: %v", err)
+	}
+	defer func() { _ = seg.Close() }()
+
+	if seg.NumLabels() != 2 {
+		t.Fatalf("NumLabels = %d, want 2", seg.NumLabels())
+	}
+
+	got, err := seg.Segment(ctx, text)
+	if err != nil {
+		t.Fatalf("Segment failed: %v", err)
+	}
+	if want := []string{"One.", " Two! Three.", " Four! Five"}; !slices.Equal(got, want) {
+		t.Errorf("Segment = %q, want %q", got, want)
+	}
+
+	byLabel, err := seg.SegmentByLabel(ctx, text, 1, 0.5)
+	if err != nil {
+		t.Fatalf("SegmentByLabel failed: %v", err)
+	}
+	var texts []string
+	for _, s := range byLabel {
+		texts = append(texts, s.Text)
+	}
+	if want := []string{"One. Two!", " Three. Four!", " Five"}; !slices.Equal(texts, want) {
+		t.Errorf("SegmentByLabel(1) = %q, want %q", texts, want)
+	}
+
+	if _, err := seg.SegmentByLabel(ctx, text, 2, 0.5); !errors.Is(err, ErrInvalidLabel) {
+		t.Errorf("SegmentByLabel(2): expected ErrInvalidLabel, got: %v", err)
+	}
+
+	probs, err := seg.Probabilities(ctx, text)
+	if err != nil {
+		t.Fatalf("Probabilities failed: %v", err)
+	}
+	for _, tp := range probs.Tokens {
+		if len(tp.Labels) != 2 || tp.Labels[0] != tp.Probability {
+			t.Errorf("token %q: Labels = %v, Probability = %v", tp.Piece, tp.Labels, tp.Probability)
+		}
+	}
+
+	// Chunked inference keeps every label aligned with its token
+	chunked, err := NewWithBackend(newBackend(4), tok, WithSentenceLabel(1))
+	if err != nil {
+		t.Fatalf("NewWithBackend() failed: %v", err)
+	}
+	defer func() { _ = chunked.Close() }()
+
+	got, err = chunked.Segment(ctx, text)
+	if err != nil {
+		t.Fatalf("Segment failed: %v", err)
+	}
+	if !slices.Equal(got, texts) {
+		t.Errorf("chunked Segment with sentence label 1 = %q, want %q", got, texts)
+	}
+}
+
+func TestNewWithBackend_InvalidSentenceLabel(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	backend := sattest.NewSentenceBackend(tok)
+
+	_, err := NewWithBackend(backend, tok, WithSentenceLabel(1))
+	if !errors.Is(err, ErrInvalidLabel) {
+		t.Errorf("expected ErrInvalidLabel, got: %v", err)
+	}
+	if !backend.Closed() {
+		t.Error("backend was not closed after failed construction")
+	}
+}
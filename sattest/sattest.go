@@ -0,0 +1,214 @@
+// Package sattest provides deterministic stand-ins for the model files used by
+// package sat, so code built on a Segmenter can be tested without downloading
+// models or installing ONNX Runtime.
+//
+//	tok := sattest.NewTokenizer(t)
+//	seg, err := sat.NewWithBackend(sattest.NewSentenceBackend(tok), tok)
+//
+// The resulting Segmenter splits after '.', '!' and '?'.
+package sattest
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/jamesainslie/go-sat/inference"
+	pb "github.com/jamesainslie/go-sat/internal/proto"
+	"github.com/jamesainslie/go-sat/tokenizer"
+)
+
+// Default logits returned by Backend.
+const (
+	DefaultBoundaryLogit float32 = 10
+	DefaultOtherLogit    float32 = -10
+)
+
+// NewTokenizer returns a small SentencePiece tokenizer covering printable
+// ASCII. Every character is its own piece, optionally preceded by ▁, so token
+// boundaries are predictable. Other characters map to <unk>.
+func NewTokenizer(tb testing.TB) *tokenizer.Tokenizer {
+	tb.Helper()
+
+	model := &pb.ModelProto{
+		Pieces: []*pb.ModelProto_SentencePiece{
+			{Piece: proto.String("<unk>"), Type: pb.ModelProto_SentencePiece_UNKNOWN.Enum()},
+			{Piece: proto.String("<s>"), Type: pb.ModelProto_SentencePiece_CONTROL.Enum()},
+			{Piece: proto.String("</s>"), Type: pb.ModelProto_SentencePiece_CONTROL.Enum()},
+		},
+	}
+	addPiece := func(piece string) {
+		model.Pieces = append(model.Pieces, &pb.ModelProto_SentencePiece{
+			Piece: proto.String(piece),
+			Score: proto.Float32(-1),
+			Type:  pb.ModelProto_SentencePiece_NORMAL.Enum(),
+		})
+	}
+	addPiece("▁")
+	for r := rune(0x21); r <= 0x7E; r++ {
+		addPiece(string(r))
+		addPiece("▁" + string(r))
+	}
+
+	data, err := proto.Marshal(model)
+	if err != nil {
+		tb.Fatalf("sattest: marshal tokenizer model: %v", err)
+	}
+	tok, err := tokenizer.NewFromBytes(data)
+	if err != nil {
+		tb.Fatalf("sattest: load tokenizer: %v", err)
+	}
+	return tok
+}
+
+// Backend is a deterministic inference.Backend. It returns BoundaryLogit for
+// token IDs in Boundaries and OtherLogit for every other token. It records
+// how it was called so tests can assert on batching.
+//
+// Backend implements inference.MultiLabelBackend. Label 0 is driven by
+// Boundaries and label k by AuxLabels[k-1].
+type Backend struct {
+	// Boundaries holds the token IDs after which a sentence ends.
+	Boundaries map[int64]bool
+
+	// AuxLabels holds, for each additional label, the token IDs for which
+	// that label fires.
+	AuxLabels []map[int64]bool
+
+	// BoundaryLogit and OtherLogit are the logits returned for boundary and
+	// non-boundary tokens.
+	BoundaryLogit float32
+	OtherLogit    float32
+
+	// MaxLen is the value reported by MaxSequenceLength.
+	MaxLen int
+
+	mu     sync.Mutex
+	calls  int
+	rows   int
+	closed bool
+}
+
+// NewBackend returns a Backend that marks the given token IDs as boundaries.
+func NewBackend(boundaryIDs ...int64) *Backend {
+	b := &Backend{
+		Boundaries:    make(map[int64]bool, len(boundaryIDs)),
+		BoundaryLogit: DefaultBoundaryLogit,
+		OtherLogit:    DefaultOtherLogit,
+		MaxLen:        inference.DefaultMaxSequenceLength,
+	}
+	for _, id := range boundaryIDs {
+		b.Boundaries[id] = true
+	}
+	return b
+}
+
+// NewSentenceBackend returns a Backend that marks the tokens tok produces for
+// '.', '!' and '?' as boundaries.
+func NewSentenceBackend(tok *tokenizer.Tokenizer) *Backend {
+	var ids []int64
+	for _, punct := range []string{".", "!", "?"} {
+		// A leading mark is encoded with ▁; one after a word without it
+		for _, text := range []string{punct, "a" + punct} {
+			encoded := tok.EncodeIDs(text)
+			ids = append(ids, int64(encoded[len(encoded)-1]))
+		}
+	}
+	return NewBackend(ids...)
+}
+
+// InferBatch returns the fixed logits of label 0 for each token of each
+// sequence.
+func (b *Backend) InferBatch(ctx context.Context, batch [][]int64, padID int64) ([][]float32, error) {
+	return b.infer(ctx, batch, 1)
+}
+
+// InferBatchLabels returns the fixed logits of every label for each token of
+// each sequence, NumLabels values per token.
+func (b *Backend) InferBatchLabels(ctx context.Context, batch [][]int64, padID int64) ([][]float32, error) {
+	return b.infer(ctx, batch, b.NumLabels())
+}
+
+// NumLabels returns 1 + len(AuxLabels).
+func (b *Backend) NumLabels() int {
+	return 1 + len(b.AuxLabels)
+}
+
+// infer returns the logits of the first labels labels.
+func (b *Backend) infer(ctx context.Context, batch [][]int64, labels int) ([][]float32, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, inference.ErrSessionClosed
+	}
+	b.calls++
+	b.rows += len(batch)
+
+	results := make([][]float32, len(batch))
+	for i, ids := range batch {
+		results[i] = make([]float32, len(ids)*labels)
+		for j, id := range ids {
+			for k := 0; k < labels; k++ {
+				fires := b.Boundaries[id]
+				if k > 0 {
+					fires = b.AuxLabels[k-1][id]
+				}
+				if fires {
+					results[i][j*labels+k] = b.BoundaryLogit
+				} else {
+					results[i][j*labels+k] = b.OtherLogit
+				}
+			}
+		}
+	}
+	return results, nil
+}
+
+// MaxSequenceLength returns MaxLen.
+func (b *Backend) MaxSequenceLength() int {
+	return b.MaxLen
+}
+
+// Close marks the backend closed; later calls to InferBatch fail.
+func (b *Backend) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	return nil
+}
+
+// Calls returns the number of InferBatch calls made so far.
+func (b *Backend) Calls() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.calls
+}
+
+// Rows returns the total number of sequences inferred so far.
+func (b *Backend) Rows() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.rows
+}
+
+// Closed reports whether Close has been called.
+func (b *Backend) Closed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.closed
+}
+
+var _ inference.MultiLabelBackend = (*Backend)(nil)
@@ -0,0 +1,100 @@
+package sattest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jamesainslie/go-sat/inference"
+)
+
+func TestNewTokenizer(t *testing.T) {
+	tok := NewTokenizer(t)
+
+	tokens := tok.Encode("Hi there.")
+	want := []string{"▁H", "i", "▁t", "h", "e", "r", "e", "."}
+	if len(tokens) != len(want) {
+		t.Fatalf("Encode() returned %d tokens, want %d", len(tokens), len(want))
+	}
+	for i, tok := range tokens {
+		if tok.Text != want[i] {
+			t.Errorf("token %d = %q, want %q", i, tok.Text, want[i])
+		}
+	}
+}
+
+func TestSentenceBackend(t *testing.T) {
+	tok := NewTokenizer(t)
+	b := NewSentenceBackend(tok)
+
+	ids := tok.EncodeIDs("Hi. Ok! ?")
+	batch := [][]int64{make([]int64, len(ids))}
+	for i, id := range ids {
+		batch[0][i] = int64(id)
+	}
+
+	got, err := b.InferBatch(context.Background(), batch, int64(tok.PadID()))
+	if err != nil {
+		t.Fatalf("InferBatch() error = %v", err)
+	}
+
+	// ▁H i . ▁O k ! ▁?
+	wantBoundary := []bool{false, false, true, false, false, true, true}
+	if len(got[0]) != len(wantBoundary) {
+		t.Fatalf("got %d logits, want %d", len(got[0]), len(wantBoundary))
+	}
+	for i, boundary := range wantBoundary {
+		want := DefaultOtherLogit
+		if boundary {
+			want = DefaultBoundaryLogit
+		}
+		if got[0][i] != want {
+			t.Errorf("logit %d = %v, want %v", i, got[0][i], want)
+		}
+	}
+
+	if b.Calls() != 1 || b.Rows() != 1 {
+		t.Errorf("Calls() = %d, Rows() = %d, want 1, 1", b.Calls(), b.Rows())
+	}
+}
+
+func TestBackend_Errors(t *testing.T) {
+	b := NewBackend()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := b.InferBatch(ctx, [][]int64{{1}}, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("InferBatch() with canceled context error = %v, want context.Canceled", err)
+	}
+
+	if err := b.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := b.InferBatch(context.Background(), [][]int64{{1}}, 1); !errors.Is(err, inference.ErrSessionClosed) {
+		t.Errorf("InferBatch() after Close error = %v, want ErrSessionClosed", err)
+	}
+	if !b.Closed() {
+		t.Error("Closed() = false after Close")
+	}
+}
+
+func TestBackend_AuxLabels(t *testing.T) {
+	b := NewBackend(7)
+	b.AuxLabels = []map[int64]bool{{8: true}}
+
+	if got := b.NumLabels(); got != 2 {
+		t.Fatalf("NumLabels() = %d, want 2", got)
+	}
+
+	got, err := b.InferBatchLabels(context.Background(), [][]int64{{7, 8, 9}}, 1)
+	if err != nil {
+		t.Fatalf("InferBatchLabels() error = %v", err)
+	}
+	hi, lo := DefaultBoundaryLogit, DefaultOtherLogit
+	want := []float32{hi, lo, lo, hi, lo, lo}
+	for i := range want {
+		if got[0][i] != want[i] {
+			t.Errorf("logit %d = %v, want %v", i, got[0][i], want[i])
+		}
+	}
+}
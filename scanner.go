@@ -0,0 +1,134 @@
+package sat
+
+import (
+	"context"
+	"errors"
+	"io"
+	"unicode/utf8"
+)
+
+// scanReadSize is the number of bytes the Scanner reads from its source at a
+// time.
+const scanReadSize = 4096
+
+// maxConsecutiveEmptyReads is the number of reads returning neither data nor
+// an error after which the Scanner fails with io.ErrNoProgress, as
+// bufio.Scanner does.
+const maxConsecutiveEmptyReads = 100
+
+// Scanner reads text from an io.Reader and yields sentences one at a time, in
+// the style of bufio.Scanner. Input is read in fixed-size blocks and fed to a
+// Stream, so only a bounded window of text is held in memory and re-inferred
+// regardless of document size. Sentence offsets are absolute positions in the
+// full input.
+//
+// A Scanner is not safe for concurrent use.
+type Scanner struct {
+	r      io.Reader
+	stream *Stream
+	buf    []byte
+	carry  []byte // trailing bytes of an incomplete UTF-8 sequence
+	queue  []Sentence
+	cur    Sentence
+	done   bool
+	err    error
+}
+
+// NewScanner returns a Scanner that segments text read from r using seg.
+// Stream options control how much right context is required before a
+// sentence is yielded and how large the pending window may grow.
+func NewScanner(r io.Reader, seg *Segmenter, opts ...StreamOption) *Scanner {
+	sc := &Scanner{
+		r:   r,
+		buf: make([]byte, scanReadSize),
+	}
+	sc.stream = seg.NewStream(func(s Sentence) {
+		sc.queue = append(sc.queue, s)
+	}, opts...)
+	return sc
+}
+
+// Scan advances to the next sentence, which is then available through
+// Sentence and Text. It returns false at the end of input or on error; Err
+// reports any error other than io.EOF.
+func (sc *Scanner) Scan(ctx context.Context) bool {
+	for len(sc.queue) == 0 {
+		if sc.done || sc.err != nil {
+			return false
+		}
+		if err := sc.fill(ctx); err != nil {
+			sc.err = err
+			return false
+		}
+	}
+
+	sc.cur = sc.queue[0]
+	sc.queue = sc.queue[1:]
+	return true
+}
+
+// fill reads the next block of input and feeds it to the stream.
+func (sc *Scanner) fill(ctx context.Context) error {
+	n, readErr := sc.read()
+	if n > 0 {
+		data := append(sc.carry, sc.buf[:n]...)
+		complete, rest := splitIncompleteRune(data)
+		sc.carry = append([]byte(nil), rest...)
+		if err := sc.stream.Write(ctx, string(complete)); err != nil {
+			return err
+		}
+	}
+
+	if errors.Is(readErr, io.EOF) {
+		sc.done = true
+		if len(sc.carry) > 0 {
+			if err := sc.stream.Write(ctx, string(sc.carry)); err != nil {
+				return err
+			}
+			sc.carry = nil
+		}
+		return sc.stream.Flush(ctx)
+	}
+	return readErr
+}
+
+// read reads the next block of input into sc.buf, retrying reads that return
+// no data and no error.
+func (sc *Scanner) read() (int, error) {
+	for range maxConsecutiveEmptyReads {
+		n, err := sc.r.Read(sc.buf)
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+	return 0, io.ErrNoProgress
+}
+
+// Sentence returns the most recent sentence produced by Scan.
+func (sc *Scanner) Sentence() Sentence {
+	return sc.cur
+}
+
+// Text returns the text of the most recent sentence produced by Scan.
+func (sc *Scanner) Text() string {
+	return sc.cur.Text
+}
+
+// Err returns the first non-EOF error encountered by the Scanner.
+func (sc *Scanner) Err() error {
+	return sc.err
+}
+
+// splitIncompleteRune splits b before a trailing UTF-8 sequence that has been
+// cut short, so that multi-byte characters spanning reads are not split.
+func splitIncompleteRune(b []byte) (complete, rest []byte) {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return b[:i], b[i:]
+			}
+			break
+		}
+	}
+	return b, nil
+}
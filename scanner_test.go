@@ -0,0 +1,98 @@
+package sat
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/jamesainslie/go-sat/sattest"
+)
+
+func TestSplitIncompleteRune(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		wantComplete string
+		wantRest     string
+	}{
+		{"ascii", "abc", "abc", ""},
+		{"complete multi-byte", "a€", "a€", ""},
+		{"cut after one byte", "a\xe2", "a", "\xe2"},
+		{"cut after two bytes", "a\xe2\x82", "a", "\xe2\x82"},
+		{"cut four-byte rune", "a\xf0\x9f\x98", "a", "\xf0\x9f\x98"},
+		{"invalid byte", "a\xff", "a\xff", ""},
+		{"empty", "", "", ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			complete, rest := splitIncompleteRune([]byte(tc.input))
+			if string(complete) != tc.wantComplete || string(rest) != tc.wantRest {
+				t.Errorf("splitIncompleteRune(%q) = %q, %q; want %q, %q",
+					tc.input, complete, rest, tc.wantComplete, tc.wantRest)
+			}
+		})
+	}
+}
+
+func TestScanner(t *testing.T) {
+	skipIfNoModel(t)
+	skipIfNoTokenizer(t)
+
+	seg, err := New(testModelPath, testTokenizerPath)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer func() { _ = seg.Close() }()
+
+	text := strings.Repeat("Hello wörld. How are you today? I am fine. ", 200)
+	sc := NewScanner(iotest.OneByteReader(strings.NewReader(text)), seg)
+
+	ctx := context.Background()
+	var sb strings.Builder
+	for sc.Scan(ctx) {
+		s := sc.Sentence()
+		if s.Text != text[s.Start:s.End] {
+			t.Fatalf("sentence offsets [%d,%d) do not match %q", s.Start, s.End, s.Text)
+		}
+		sb.WriteString(sc.Text())
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if sb.String() != text {
+		t.Error("scanned sentences do not rejoin to the input")
+	}
+}
+
+// emptyReader returns no data and no error, like a broken io.Reader.
+type emptyReader struct{ reads int }
+
+func (r *emptyReader) Read([]byte) (int, error) {
+	r.reads++
+	return 0, nil
+}
+
+func TestScanner_NoProgress(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	seg, err := NewWithBackend(sattest.NewSentenceBackend(tok), tok)
+	if err != nil {
+		t.Fatalf("NewWithBackend() failed: %v", err)
+	}
+	defer func() { _ = seg.Close() }()
+
+	r := &emptyReader{}
+	sc := NewScanner(r, seg)
+	if sc.Scan(context.Background()) {
+		t.Fatalf("Scan returned a sentence: %q", sc.Text())
+	}
+	if err := sc.Err(); !errors.Is(err, io.ErrNoProgress) {
+		t.Errorf("Err() = %v, want io.ErrNoProgress", err)
+	}
+	if r.reads != maxConsecutiveEmptyReads {
+		t.Errorf("read %d times, want %d", r.reads, maxConsecutiveEmptyReads)
+	}
+}
@@ -2,27 +2,49 @@
 """Generate golden test files for go-sat tokenizer validation."""
 
 import json
-from transformers import AutoTokenizer
+import sys
+
+GOLDEN_PATH = "testdata/tokenizer_golden.json"
+
+TEST_CASES = [
+    "Hello",
+    "Hello world",
+    "Hello world.",
+    "I want to",
+    "Thank you very much.",
+    "This is a test sentence.",
+    "The quick brown fox jumps over the lazy dog.",
+    "",  # empty string
+    "café",  # non-ASCII
+    "你好世界",  # Chinese
+    "🎉",  # emoji
+    # Rules from the model's precompiled_charsmap (NFKC)
+    "Ｈｅｌｌｏ ｗｏｒｌｄ．",  # full-width forms
+    "Ｔｈａｎｋ\u3000ｙｏｕ ｖｅｒｙ ｍｕｃｈ．",  # ideographic space
+    "cafe\u0301",  # combining accent
+    "naïve résumé",  # precomposed accents
+    "ﬁne ﬂow",  # ligatures
+    "①②③",  # enclosed numbers
+    "Hello 🎉",  # emoji after a space
+    "👍🏽 thumbs up",  # emoji with skin tone modifier
+]
+
+def check():
+    """Report the test cases missing from the golden file."""
+    with open(GOLDEN_PATH) as f:
+        have = {case["input"] for case in json.load(f)}
+    missing = [text for text in TEST_CASES if text not in have]
+    for text in missing:
+        print(f"missing: {text!r}")
+    return 1 if missing else 0
 
 def main():
-    tok = AutoTokenizer.from_pretrained("xlm-roberta-base")
+    from transformers import AutoTokenizer
 
-    test_cases = [
-        "Hello",
-        "Hello world",
-        "Hello world.",
-        "I want to",
-        "Thank you very much.",
-        "This is a test sentence.",
-        "The quick brown fox jumps over the lazy dog.",
-        "",  # empty string
-        "café",  # non-ASCII
-        "你好世界",  # Chinese
-        "🎉",  # emoji
-    ]
+    tok = AutoTokenizer.from_pretrained("xlm-roberta-base")
 
     results = []
-    for text in test_cases:
+    for text in TEST_CASES:
         enc = tok(text, return_offsets_mapping=True, add_special_tokens=False)
         results.append({
             "input": text,
@@ -31,10 +53,12 @@ def main():
             "tokens": tok.convert_ids_to_tokens(enc["input_ids"]),
         })
 
-    with open("testdata/tokenizer_golden.json", "w") as f:
+    with open(GOLDEN_PATH, "w") as f:
         json.dump(results, f, indent=2, ensure_ascii=False)
 
     print(f"Generated {len(results)} test cases")
 
 if __name__ == "__main__":
+    if sys.argv[1:] == ["--check"]:
+        sys.exit(check())
     main()
@@ -0,0 +1,147 @@
+package sat
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jamesainslie/go-sat/internal/punct"
+	"github.com/jamesainslie/go-sat/tokenizer"
+)
+
+// Sentence describes one segment of the input text.
+//
+// Byte offsets index the original string, so Text == text[Start:End].
+// Consecutive sentences are contiguous: each Start equals the previous End,
+// unless whitespace is stripped or whitespace-only sentences are dropped.
+type Sentence struct {
+	// Text is the sentence exactly as it appears in the input, including any
+	// surrounding whitespace unless it is stripped.
+	Text string
+
+	// Trimmed is Text without leading and trailing whitespace.
+	Trimmed string
+
+	// Start and End are byte offsets of the sentence in the input.
+	Start int
+	End   int
+
+	// TrimmedStart and TrimmedEnd are byte offsets of Trimmed in the input.
+	TrimmedStart int
+	TrimmedEnd   int
+
+	// RuneStart and RuneEnd are rune (code point) offsets of the sentence.
+	RuneStart int
+	RuneEnd   int
+
+	// TokenStart and TokenEnd delimit the half-open range of token indices
+	// that fall inside the sentence. A token that continues past full-width
+	// terminal punctuation ending the sentence counts for this sentence,
+	// although the rest of its text starts the next one.
+	TokenStart int
+	TokenEnd   int
+
+	// Probability is the boundary probability of the token that ended the
+	// sentence. For a final segment that was not closed by a boundary it is
+	// the probability of its last token, or 0 if it contains no tokens.
+	Probability float32
+}
+
+// thresholdSplits returns the indices of tokens whose boundary probability
+// exceeds threshold.
+func thresholdSplits(probs []float32, threshold float32) []int {
+	var splits []int
+	for i, p := range probs {
+		if p > threshold {
+			splits = append(splits, i)
+		}
+	}
+	return splits
+}
+
+// buildSentences splits text after each token index in splits (ascending).
+// Splits that would produce an empty sentence are ignored, and any text after
+// the last split becomes a final sentence, so the result covers text exactly.
+// In scripts written without spaces a token may run past the punctuation that
+// ends a sentence, e.g. "。我"; the split is then placed right after the
+// punctuation (see splitEnd).
+func buildSentences(text string, tokens []tokenizer.TokenInfo, probs []float32, splits []int) []Sentence {
+	var sentences []Sentence
+	start, runeStart, tokStart := 0, 0, 0
+
+	emit := func(end, tokEnd int, prob float32) {
+		sent := newSentence(text, start, end, runeStart)
+		sent.TokenStart = tokStart
+		sent.TokenEnd = tokEnd
+		sent.Probability = prob
+		sentences = append(sentences, sent)
+		start, runeStart, tokStart = end, sent.RuneEnd, tokEnd
+	}
+
+	for _, i := range splits {
+		if i < 0 || i >= len(tokens) || i >= len(probs) {
+			continue
+		}
+		end := splitEnd(text, tokens[i])
+		if end > start && end <= len(text) {
+			emit(end, i+1, probs[i])
+		}
+	}
+
+	if start < len(text) {
+		var prob float32
+		if last := len(tokens) - 1; last >= tokStart && last < len(probs) {
+			prob = probs[last]
+		}
+		emit(len(text), len(tokens), prob)
+	}
+
+	return sentences
+}
+
+// splitEnd returns the byte offset at which a split after tok ends the
+// sentence. If the token's text continues past full-width terminal
+// punctuation, such as 。 or ！, and any closing quotes and brackets after
+// it, the sentence ends there; otherwise it ends with the token.
+func splitEnd(text string, tok tokenizer.TokenInfo) int {
+	if tok.Start < 0 || tok.Start >= tok.End || tok.End > len(text) {
+		return tok.End
+	}
+
+	piece := text[tok.Start:tok.End]
+	end := -1
+	for i, r := range piece {
+		switch {
+		case punct.IsFullWidthTerminal(r):
+			end = i + utf8.RuneLen(r)
+		case end == i && isClosing(r):
+			end = i + utf8.RuneLen(r) // keep 」 or ） with the sentence
+		}
+	}
+	if end <= 0 || end == len(piece) {
+		return tok.End
+	}
+	return tok.Start + end
+}
+
+// newSentence returns the sentence covering text[start:end] with its text,
+// trimmed view and rune offsets filled in.
+func newSentence(text string, start, end, runeStart int) Sentence {
+	raw := text[start:end]
+	trimmedStart := start + len(raw) - len(strings.TrimLeftFunc(raw, unicode.IsSpace))
+	trimmedEnd := start + len(strings.TrimRightFunc(raw, unicode.IsSpace))
+	if trimmedEnd < trimmedStart {
+		trimmedEnd = trimmedStart
+	}
+
+	return Sentence{
+		Text:         raw,
+		Trimmed:      text[trimmedStart:trimmedEnd],
+		Start:        start,
+		End:          end,
+		TrimmedStart: trimmedStart,
+		TrimmedEnd:   trimmedEnd,
+		RuneStart:    runeStart,
+		RuneEnd:      runeStart + utf8.RuneCountInString(raw),
+	}
+}
@@ -0,0 +1,257 @@
+package sat
+
+import (
+	"context"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jamesainslie/go-sat/internal/proto"
+	"github.com/jamesainslie/go-sat/sattest"
+	"github.com/jamesainslie/go-sat/tokenizer"
+)
+
+func TestBuildSentences(t *testing.T) {
+	tok, err := tokenizer.New(writeTestTokenizer(t))
+	if err != nil {
+		t.Fatalf("tokenizer.New failed: %v", err)
+	}
+
+	text := " Hello world. How are you?  "
+	tokens := tok.Encode(text)
+	probs := make([]float32, len(tokens))
+	var splits []int
+	for i, tk := range tokens {
+		if tk.Text == "." || tk.Text == "?" {
+			probs[i] = 0.9
+			splits = append(splits, i)
+		}
+	}
+
+	got := buildSentences(text, tokens, probs, splits)
+	want := []Sentence{
+		{
+			Text: " Hello world.", Trimmed: "Hello world.",
+			Start: 0, End: 13, TrimmedStart: 1, TrimmedEnd: 13,
+			RuneStart: 0, RuneEnd: 13, TokenStart: 0, TokenEnd: 3, Probability: 0.9,
+		},
+		{
+			Text: " How are you?", Trimmed: "How are you?",
+			Start: 13, End: 26, TrimmedStart: 14, TrimmedEnd: 26,
+			RuneStart: 13, RuneEnd: 26, TokenStart: 3, TokenEnd: 7, Probability: 0.9,
+		},
+		{
+			Text: "  ", Trimmed: "",
+			Start: 26, End: 28, TrimmedStart: 28, TrimmedEnd: 28,
+			RuneStart: 26, RuneEnd: 28, TokenStart: 7, TokenEnd: 7, Probability: 0,
+		},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d sentences %+v, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("sentence %d:\n got  %+v\n want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestBuildSentences_IgnoresEmptySplits(t *testing.T) {
+	text := "Hi. Yo."
+	tokens := []tokenizer.TokenInfo{
+		{Text: "▁Hi", Start: 0, End: 2},
+		{Text: ".", Start: 2, End: 3},
+		{Text: "▁Yo", Start: 4, End: 6},
+		{Text: ".", Start: 6, End: 7},
+	}
+	probs := []float32{0.1, 0.8, 0.2, 0.7}
+
+	got := buildSentences(text, tokens, probs, []int{1, 1, 3, 9})
+	if len(got) != 2 {
+		t.Fatalf("got %d sentences %+v, want 2", len(got), got)
+	}
+	if got[0].Text != "Hi." || got[1].Text != " Yo." {
+		t.Errorf("got %q and %q", got[0].Text, got[1].Text)
+	}
+	if got[1].Probability != 0.7 || got[1].TokenStart != 2 || got[1].TokenEnd != 4 {
+		t.Errorf("second sentence = %+v", got[1])
+	}
+}
+
+func TestBuildSentences_RejoinProperty(t *testing.T) {
+	tok, err := tokenizer.New(writeTestTokenizer(t))
+	if err != nil {
+		t.Fatalf("tokenizer.New failed: %v", err)
+	}
+
+	alphabet := []string{"Hello", " world", ".", "?", " ", "  ", "\n", "é", "€", "日本", "。", "a", "b", "\t", "\xff"}
+	rng := rand.New(rand.NewPCG(7, 11))
+
+	for iter := 0; iter < 1000; iter++ {
+		var sb strings.Builder
+		for n := 1 + rng.IntN(30); n > 0; n-- {
+			sb.WriteString(alphabet[rng.IntN(len(alphabet))])
+		}
+		text := sb.String()
+
+		tokens := tok.Encode(text)
+		if len(tokens) == 0 {
+			continue
+		}
+		probs := make([]float32, len(tokens))
+		for i := range probs {
+			probs[i] = rng.Float32()
+		}
+
+		sentences := buildSentences(text, tokens, probs, thresholdSplits(probs, 0.5))
+
+		var sb2 strings.Builder
+		prevEnd, prevRune, prevTok := 0, 0, 0
+		for i, sent := range sentences {
+			sb2.WriteString(sent.Text)
+			if sent.Start != prevEnd || sent.RuneStart != prevRune || sent.TokenStart != prevTok {
+				t.Fatalf("%q: sentence %d is not contiguous: %+v", text, i, sent)
+			}
+			if sent.Text != text[sent.Start:sent.End] || sent.Trimmed != text[sent.TrimmedStart:sent.TrimmedEnd] {
+				t.Fatalf("%q: sentence %d offsets do not match text: %+v", text, i, sent)
+			}
+			if sent.End < len(text) && !utf8.RuneStart(text[sent.End]) {
+				t.Fatalf("%q: sentence %d ends inside a character at %d", text, i, sent.End)
+			}
+			prevEnd, prevRune, prevTok = sent.End, sent.RuneEnd, sent.TokenEnd
+		}
+
+		if got := sb2.String(); got != text {
+			t.Fatalf("rejoined %q, want %q", got, text)
+		}
+		if prevRune != utf8.RuneCountInString(text) || prevTok != len(tokens) {
+			t.Fatalf("%q: sentences end at rune %d token %d", text, prevRune, prevTok)
+		}
+	}
+}
+
+func TestBuildSentences_FullWidthPunctuation(t *testing.T) {
+	// Tokens of scripts without spaces may run past the end of a sentence
+	text := "今天很好。我们走吧！「好。」他说"
+	pieces := []string{"今天", "很好", "。我们", "走吧", "！", "「好", "。」他", "说"}
+	var tokens []tokenizer.TokenInfo
+	offset := 0
+	for _, p := range pieces {
+		tokens = append(tokens, tokenizer.TokenInfo{Text: p, Start: offset, End: offset + len(p)})
+		offset += len(p)
+	}
+	probs := []float32{0, 0, 0.9, 0, 0.8, 0, 0.7, 0.1}
+
+	got := buildSentences(text, tokens, probs, []int{2, 4, 6})
+	want := []string{"今天很好。", "我们走吧！", "「好。」", "他说"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d sentences %+v, want %d", len(got), got, len(want))
+	}
+	runes := 0
+	for i, sent := range got {
+		if sent.Text != want[i] {
+			t.Errorf("sentence %d = %q, want %q", i, sent.Text, want[i])
+		}
+		if sent.RuneStart != runes || sent.RuneEnd != runes+utf8.RuneCountInString(want[i]) {
+			t.Errorf("sentence %d runes [%d, %d), want start %d", i, sent.RuneStart, sent.RuneEnd, runes)
+		}
+		runes = sent.RuneEnd
+	}
+	// The straddling token counts for the sentence it ends
+	if got[0].TokenEnd != 3 || got[1].TokenStart != 3 || got[0].Probability != 0.9 {
+		t.Errorf("first sentences = %+v, %+v", got[0], got[1])
+	}
+}
+
+func TestSplitEnd(t *testing.T) {
+	tests := []struct {
+		piece string
+		want  int
+	}{
+		{"。", 3},    // punctuation ends the token
+		{"好。", 6},   // likewise
+		{"。我", 3},   // split inside the token
+		{"。」他", 6},  // closing bracket stays with the sentence
+		{"a.b", 3},  // ASCII periods are left to the model
+		{"。我。", 9},  // the last terminal ends the token
+		{"！？他们", 6}, // runs of terminals
+		{"　。x", 6},  // ideographic space before the terminal
+	}
+	for _, tc := range tests {
+		text := "前" + tc.piece
+		tok := tokenizer.TokenInfo{Start: len("前"), End: len(text)}
+		if got := splitEnd(text, tok) - tok.Start; got != tc.want {
+			t.Errorf("splitEnd(%q) = %d, want %d", tc.piece, got, tc.want)
+		}
+	}
+}
+
+// newCJKSegmenter returns a Segmenter over a vocabulary of Chinese words in
+// which 。 and ！ are only tokenized together with the following word, so
+// tokens straddle sentence boundaries. Every token holding 。 or ！ is a
+// boundary.
+func newCJKSegmenter(t *testing.T, opts ...Option) *Segmenter {
+	t.Helper()
+
+	model := &pb.ModelProto{
+		Pieces: []*pb.ModelProto_SentencePiece{
+			{Piece: proto.String("<unk>"), Type: pb.ModelProto_SentencePiece_UNKNOWN.Enum()},
+			{Piece: proto.String("<s>"), Type: pb.ModelProto_SentencePiece_CONTROL.Enum()},
+			{Piece: proto.String("</s>"), Type: pb.ModelProto_SentencePiece_CONTROL.Enum()},
+		},
+	}
+	words := []string{"你好", "我们", "走吧", "今天", "很好"}
+	pieces := []string{"▁", "。", "！"}
+	for _, w := range words {
+		pieces = append(pieces, w, "▁"+w, "。"+w, "！"+w)
+	}
+	for _, p := range pieces {
+		model.Pieces = append(model.Pieces, &pb.ModelProto_SentencePiece{
+			Piece: proto.String(p),
+			Score: proto.Float32(-1),
+			Type:  pb.ModelProto_SentencePiece_NORMAL.Enum(),
+		})
+	}
+	data, err := proto.Marshal(model)
+	if err != nil {
+		t.Fatalf("marshal tokenizer model: %v", err)
+	}
+	tok, err := tokenizer.NewFromBytes(data)
+	if err != nil {
+		t.Fatalf("tokenizer.NewFromBytes failed: %v", err)
+	}
+
+	var boundaries []int64
+	for _, p := range pieces {
+		if strings.ContainsAny(p, "。！") {
+			for _, info := range tok.Encode("你好" + p) {
+				if info.Text == p {
+					boundaries = append(boundaries, int64(info.ID))
+				}
+			}
+		}
+	}
+	seg, err := NewWithBackend(sattest.NewBackend(boundaries...), tok, opts...)
+	if err != nil {
+		t.Fatalf("NewWithBackend failed: %v", err)
+	}
+	return seg
+}
+
+func TestSegment_StraddlingTokens(t *testing.T) {
+	seg := newCJKSegmenter(t)
+
+	got, err := seg.Segment(context.Background(), "今天很好。我们走吧！你好。")
+	if err != nil {
+		t.Fatalf("Segment() failed: %v", err)
+	}
+	want := []string{"今天很好。", "我们走吧！", "你好。"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Segment() = %q, want %q", got, want)
+	}
+}
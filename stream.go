@@ -0,0 +1,246 @@
+package sat
+
+import (
+	"context"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/jamesainslie/go-sat/tokenizer"
+)
+
+// StreamOption configures a Stream.
+type StreamOption func(*streamConfig)
+
+type streamConfig struct {
+	rightContext int
+	maxBuffer    int
+}
+
+func defaultStreamConfig() streamConfig {
+	return streamConfig{
+		rightContext: 4,
+		maxBuffer:    2048,
+	}
+}
+
+// WithRightContext sets how many tokens must follow a boundary before the
+// sentence it ends is emitted (default: 4). More context makes boundaries
+// more stable at the cost of latency.
+func WithRightContext(tokens int) StreamOption {
+	return func(c *streamConfig) {
+		if tokens >= 0 {
+			c.rightContext = tokens
+		}
+	}
+}
+
+// WithMaxBuffer sets the maximum number of bytes of pending text kept for
+// re-inference (default: 2048). When the pending text grows beyond this
+// without a boundary above threshold, the stream splits at its most probable
+// boundary instead.
+func WithMaxBuffer(bytes int) StreamOption {
+	return func(c *streamConfig) {
+		if bytes > 0 {
+			c.maxBuffer = bytes
+		}
+	}
+}
+
+// Stream segments text that arrives incrementally, such as LLM output or ASR
+// transcripts. Text is appended with Write; each sentence is passed to the
+// emit callback once enough text follows its boundary for the boundary to be
+// stable. Only the text after the last emitted sentence is kept and
+// re-inferred on each Write. Since each Write infers a different prefix, a
+// Stream does not use the logit cache.
+//
+// Emitted sentences carry offsets relative to the start of the stream, and
+// their token ranges count tokens across the whole stream.
+//
+// Stream is safe for concurrent use. emit is called from Write and Flush, in
+// order and never concurrently, without the stream's lock held, so it may
+// call Write or Flush itself. Sentences produced while another call is
+// emitting are passed on by that call, so Write may return before its
+// sentences have been emitted.
+type Stream struct {
+	seg  *Segmenter
+	emit func(Sentence)
+	cfg  streamConfig
+
+	mu          sync.Mutex
+	pending     string
+	byteOffset  int
+	runeOffset  int
+	tokenOffset int
+	queue       []Sentence // finalized sentences not yet emitted
+	emitting    bool       // whether a call is emitting the queue
+}
+
+// NewStream returns a Stream that segments incremental text with s and passes
+// each finalized sentence to emit.
+//
+// The stream splits at the Segmenter's threshold and applies its whitespace
+// and punctuation options, such as WithStripWhitespace and
+// WithAttachClosing. Options that need the whole text do not apply: length
+// limits, adaptive thresholds such as WithCharsPerSentence, and the options
+// registered with WithLanguageOptions.
+func (s *Segmenter) NewStream(emit func(Sentence), opts ...StreamOption) *Stream {
+	cfg := defaultStreamConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return &Stream{
+		seg:  s,
+		emit: emit,
+		cfg:  cfg,
+	}
+}
+
+// Write appends text to the stream and emits any sentences whose boundaries
+// have become stable.
+func (st *Stream) Write(ctx context.Context, text string) error {
+	st.mu.Lock()
+	st.pending += text
+	err := st.process(ctx, false)
+	st.drain()
+	return err
+}
+
+// Flush emits all pending text as sentences, treating it as the end of input.
+func (st *Stream) Flush(ctx context.Context) error {
+	st.mu.Lock()
+	err := st.process(ctx, true)
+	st.drain()
+	return err
+}
+
+// drain emits the queued sentences unless another call is already doing so,
+// releasing st.mu around each call to emit. It is called with st.mu held and
+// returns with it released.
+func (st *Stream) drain() {
+	if st.emitting {
+		st.mu.Unlock()
+		return
+	}
+	st.emitting = true
+	for len(st.queue) > 0 {
+		sent := st.queue[0]
+		st.queue = st.queue[1:]
+		st.mu.Unlock()
+		st.emit(sent)
+		st.mu.Lock()
+	}
+	st.queue = nil
+	st.emitting = false
+	st.mu.Unlock()
+}
+
+// process segments the pending text and queues finalized sentences. When
+// final is true every remaining sentence is queued.
+func (st *Stream) process(ctx context.Context, final bool) error {
+	if st.pending == "" {
+		return nil
+	}
+
+	tokens, probs, err := st.seg.streamProbabilities(ctx, st.pending)
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		if final {
+			st.advance(len(st.pending), 0)
+		}
+		return nil
+	}
+
+	cc := st.seg.defaults
+	text := st.pending
+	splits, probs := cc.adjustSplits(text, tokens, probs, thresholdSplits(probs, cc.threshold))
+	if !final {
+		splits = stableSplits(splits, len(tokens), st.cfg.rightContext)
+		if len(splits) == 0 && len(text) > st.cfg.maxBuffer {
+			if split := forcedSplit(probs, st.cfg.rightContext); split >= 0 {
+				splits, probs = cc.adjustSplits(text, tokens, probs, []int{split})
+			}
+		}
+		if len(splits) == 0 {
+			return nil
+		}
+
+		// Only emit up to the last stable boundary; the rest stays pending.
+		// A token running past full-width punctuation, such as "。我们", is
+		// cut after the punctuation, and buildSentences closes the final
+		// sentence at the end of text.
+		last := splits[len(splits)-1]
+		text = text[:splitEnd(text, tokens[last])]
+		tokens, probs = tokens[:last+1], probs[:last+1]
+	}
+
+	// The offsets of sentences are relative to the pending text, which is
+	// consumed up to the end of the last sentence before any whitespace is
+	// stripped
+	sentences := buildSentences(text, tokens, probs, splits)
+	if len(sentences) == 0 {
+		return nil
+	}
+	last := sentences[len(sentences)-1]
+
+	for _, sent := range cc.finishSentences(text, sentences) {
+		sent.Start += st.byteOffset
+		sent.End += st.byteOffset
+		sent.TrimmedStart += st.byteOffset
+		sent.TrimmedEnd += st.byteOffset
+		sent.RuneStart += st.runeOffset
+		sent.RuneEnd += st.runeOffset
+		sent.TokenStart += st.tokenOffset
+		sent.TokenEnd += st.tokenOffset
+		st.queue = append(st.queue, sent)
+	}
+	st.advance(last.End, last.TokenEnd)
+	return nil
+}
+
+// streamProbabilities is like tokenProbabilities but bypasses the logit
+// cache, which would otherwise fill with stream prefixes that are never
+// looked up again.
+func (s *Segmenter) streamProbabilities(ctx context.Context, text string) ([]tokenizer.TokenInfo, []float32, error) {
+	tokens := s.tokenizer.Encode(text)
+	if len(tokens) == 0 {
+		return nil, nil, nil
+	}
+	logits, err := s.getLogitsBatch(ctx, [][]tokenizer.TokenInfo{tokens})
+	if err != nil {
+		return nil, nil, err
+	}
+	return tokens, labelColumn(sigmoidAll(logits[0]), s.numLabels, s.label), nil
+}
+
+// advance drops the first n bytes (covering tokens tokens) of pending text.
+func (st *Stream) advance(n, tokens int) {
+	st.runeOffset += utf8.RuneCountInString(st.pending[:n])
+	st.byteOffset += n
+	st.tokenOffset += tokens
+	st.pending = st.pending[n:]
+}
+
+// stableSplits returns the splits that are followed by at least rightContext
+// tokens, out of numTokens in total.
+func stableSplits(splits []int, numTokens, rightContext int) []int {
+	n := 0
+	for n < len(splits) && numTokens-1-splits[n] >= rightContext {
+		n++
+	}
+	return splits[:n]
+}
+
+// forcedSplit returns the index of the most probable boundary token that has
+// at least rightContext tokens after it, or -1 if there is none.
+func forcedSplit(probs []float32, rightContext int) int {
+	best := -1
+	for i := 0; i < len(probs)-rightContext; i++ {
+		if best < 0 || probs[i] > probs[best] {
+			best = i
+		}
+	}
+	return best
+}
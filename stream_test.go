@@ -0,0 +1,246 @@
+package sat
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jamesainslie/go-sat/sattest"
+)
+
+func TestStableSplits(t *testing.T) {
+	tests := []struct {
+		name         string
+		splits       []int
+		numTokens    int
+		rightContext int
+		want         []int
+	}{
+		{"no splits", nil, 10, 4, nil},
+		{"all stable", []int{1, 4}, 10, 4, []int{1, 4}},
+		{"last too close", []int{1, 7}, 10, 4, []int{1}},
+		{"exact context", []int{5}, 10, 4, []int{5}},
+		{"zero context", []int{9}, 10, 0, []int{9}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := stableSplits(tc.splits, tc.numTokens, tc.rightContext)
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("stableSplits() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestForcedSplit(t *testing.T) {
+	probs := []float32{0.1, 0.3, 0.2, 0.9, 0.8}
+
+	if got := forcedSplit(probs, 0); got != 3 {
+		t.Errorf("forcedSplit(context 0) = %d, want 3", got)
+	}
+	if got := forcedSplit(probs, 2); got != 1 {
+		t.Errorf("forcedSplit(context 2) = %d, want 1", got)
+	}
+	if got := forcedSplit(probs, 5); got != -1 {
+		t.Errorf("forcedSplit(context 5) = %d, want -1", got)
+	}
+}
+
+func TestStream_WriteFlush(t *testing.T) {
+	skipIfNoModel(t)
+	skipIfNoTokenizer(t)
+
+	seg, err := New(testModelPath, testTokenizerPath)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer func() { _ = seg.Close() }()
+
+	var got []Sentence
+	stream := seg.NewStream(func(s Sentence) { got = append(got, s) })
+
+	ctx := context.Background()
+	text := "Hello world. How are you today? I am fine, thanks for asking."
+	for _, word := range strings.SplitAfter(text, " ") {
+		if err := stream.Write(ctx, word); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if err := stream.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	var sb strings.Builder
+	for i, s := range got {
+		if s.Text != text[s.Start:s.End] {
+			t.Errorf("sentence %d offsets [%d,%d) do not match %q", i, s.Start, s.End, s.Text)
+		}
+		sb.WriteString(s.Text)
+	}
+	if sb.String() != text {
+		t.Errorf("stream sentences rejoin to %q, want %q", sb.String(), text)
+	}
+}
+
+func TestStream_Offsets(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	seg, err := NewWithBackend(sattest.NewSentenceBackend(tok), tok)
+	if err != nil {
+		t.Fatalf("NewWithBackend() failed: %v", err)
+	}
+
+	var got []Sentence
+	stream := seg.NewStream(func(s Sentence) { got = append(got, s) }, WithRightContext(1))
+
+	ctx := context.Background()
+	text := "Hello world. How are you today? I am fine, thanks for asking."
+	for _, word := range strings.SplitAfter(text, " ") {
+		if err := stream.Write(ctx, word); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if err := stream.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("got %d sentences, want 3: %+v", len(got), got)
+	}
+	for i, s := range got {
+		if s.Text != text[s.Start:s.End] {
+			t.Errorf("sentence %d offsets [%d,%d) do not match %q", i, s.Start, s.End, s.Text)
+		}
+		if i > 0 && s.Start != got[i-1].End {
+			t.Errorf("sentence %d starts at %d, want %d", i, s.Start, got[i-1].End)
+		}
+	}
+}
+
+func TestStream_ReentrantEmit(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	seg, err := NewWithBackend(sattest.NewSentenceBackend(tok), tok)
+	if err != nil {
+		t.Fatalf("NewWithBackend() failed: %v", err)
+	}
+
+	ctx := context.Background()
+	var got []string
+	var stream *Stream
+	stream = seg.NewStream(func(s Sentence) {
+		got = append(got, s.Trimmed)
+		// Emit is called without the lock held, so this does not deadlock
+		if len(got) == 1 {
+			if err := stream.Write(ctx, "Echo one. Echo two. "); err != nil {
+				t.Errorf("Write from emit failed: %v", err)
+			}
+		}
+	}, WithRightContext(0))
+
+	if err := stream.Write(ctx, "First. Second. "); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := stream.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	want := []string{"First.", "Second.", "Echo one.", "Echo two."}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestStream_StraddlingTokens(t *testing.T) {
+	seg := newCJKSegmenter(t)
+
+	var got []string
+	stream := seg.NewStream(func(s Sentence) { got = append(got, s.Text) }, WithRightContext(1))
+	ctx := context.Background()
+
+	// "！你好" is followed by one token, so only the text up to ！ is stable
+	if err := stream.Write(ctx, "今天很好。我们走吧！你好。"); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	if want := []string{"今天很好。", "我们走吧！"}; !slices.Equal(got, want) {
+		t.Errorf("after Write got %q, want %q", got, want)
+	}
+	if err := stream.Write(ctx, "我们走吧！"); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	if err := stream.Flush(ctx); err != nil {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+	want := []string{"今天很好。", "我们走吧！", "你好。", "我们走吧！"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestStream_SegmenterOptions(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	seg, err := NewWithBackend(sattest.NewSentenceBackend(tok), tok, WithAttachClosing(true), WithStripWhitespace(true))
+	if err != nil {
+		t.Fatalf("NewWithBackend() failed: %v", err)
+	}
+	defer func() { _ = seg.Close() }()
+
+	var got []Sentence
+	stream := seg.NewStream(func(s Sentence) { got = append(got, s) }, WithRightContext(1))
+
+	ctx := context.Background()
+	text := `He said "Hi." Then he left. Ok.`
+	for _, word := range strings.SplitAfter(text, " ") {
+		if err := stream.Write(ctx, word); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if err := stream.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	var texts []string
+	for i, s := range got {
+		texts = append(texts, s.Text)
+		if s.Text != text[s.Start:s.End] {
+			t.Errorf("sentence %d offsets [%d,%d) do not match %q", i, s.Start, s.End, s.Text)
+		}
+	}
+	want := []string{`He said "Hi."`, "Then he left.", "Ok."}
+	if !slices.Equal(texts, want) {
+		t.Errorf("got %q, want %q", texts, want)
+	}
+
+	direct, err := seg.Segment(ctx, text)
+	if err != nil {
+		t.Fatalf("Segment failed: %v", err)
+	}
+	if !slices.Equal(texts, direct) {
+		t.Errorf("stream %q differs from Segment %q", texts, direct)
+	}
+}
+
+func TestStream_BypassesLogitCache(t *testing.T) {
+	tok := sattest.NewTokenizer(t)
+	cache := NewLogitCache(1 << 20)
+	seg, err := NewWithBackend(sattest.NewSentenceBackend(tok), tok, WithLogitCache(cache))
+	if err != nil {
+		t.Fatalf("NewWithBackend() failed: %v", err)
+	}
+	defer func() { _ = seg.Close() }()
+
+	stream := seg.NewStream(func(Sentence) {})
+	ctx := context.Background()
+	for _, word := range strings.SplitAfter("One two. Three four. Five.", " ") {
+		if err := stream.Write(ctx, word); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if err := stream.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	if stats := cache.Stats(); stats != (CacheStats{}) {
+		t.Errorf("cache stats = %+v, want the cache untouched", stats)
+	}
+}
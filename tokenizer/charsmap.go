@@ -0,0 +1,85 @@
+package tokenizer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidCharsmap indicates a precompiled_charsmap in the model's
+// NormalizerSpec that could not be decoded.
+var ErrInvalidCharsmap = errors.New("tokenizer: invalid precompiled charsmap")
+
+// charsmap is SentencePiece's precompiled normalization rule set, such as the
+// NFKC-based nmt_nfkc rules of XLM-RoBERTa. It maps input prefixes to
+// replacement strings through a Darts-clone double-array trie.
+//
+// The serialized form is a little-endian uint32 giving the trie's size in
+// bytes, the trie's uint32 units, and a blob of NUL-terminated replacements
+// that the trie's values index.
+type charsmap struct {
+	units        []uint32
+	replacements []byte
+}
+
+// parseCharsmap decodes a precompiled_charsmap.
+func parseCharsmap(data []byte) (*charsmap, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("%w: %d bytes", ErrInvalidCharsmap, len(data))
+	}
+	size := binary.LittleEndian.Uint32(data)
+	if size%4 != 0 || uint64(size) > uint64(len(data)-4) {
+		return nil, fmt.Errorf("%w: trie size %d exceeds %d bytes", ErrInvalidCharsmap, size, len(data)-4)
+	}
+
+	trie := data[4 : 4+size]
+	units := make([]uint32, len(trie)/4)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint32(trie[i*4:])
+	}
+	if len(units) == 0 {
+		return nil, fmt.Errorf("%w: empty trie", ErrInvalidCharsmap)
+	}
+
+	return &charsmap{units: units, replacements: data[4+size:]}, nil
+}
+
+// Darts-clone unit layout.
+func unitHasLeaf(u uint32) bool  { return (u>>8)&1 == 1 }
+func unitValue(u uint32) uint32  { return u & (1<<31 - 1) }
+func unitLabel(u uint32) uint32  { return u & (1<<31 | 0xFF) }
+func unitOffset(u uint32) uint32 { return (u >> 10) << ((u & (1 << 9)) >> 6) }
+
+// longestPrefix returns the replacement for the longest prefix of s that the
+// trie holds, and that prefix's length in bytes. n is 0 if no prefix matches.
+func (c *charsmap) longestPrefix(s string) (replacement string, n int) {
+	pos := unitOffset(c.units[0])
+	value := uint32(0)
+	for i := 0; i < len(s); i++ {
+		pos ^= uint32(s[i])
+		if pos >= uint32(len(c.units)) {
+			break
+		}
+		u := c.units[pos]
+		if unitLabel(u) != uint32(s[i]) {
+			break
+		}
+		pos ^= unitOffset(u)
+		if unitHasLeaf(u) {
+			if pos >= uint32(len(c.units)) {
+				break
+			}
+			value, n = unitValue(c.units[pos]), i+1
+		}
+	}
+	if n == 0 || value >= uint32(len(c.replacements)) {
+		return "", 0
+	}
+
+	rest := c.replacements[value:]
+	if end := bytes.IndexByte(rest, 0); end >= 0 {
+		rest = rest[:end]
+	}
+	return string(rest), n
+}
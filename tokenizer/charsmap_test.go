@@ -0,0 +1,223 @@
+package tokenizer
+
+import (
+	"encoding/binary"
+	"errors"
+	"slices"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jamesainslie/go-sat/internal/proto"
+)
+
+// buildCharsmap serializes rules as a precompiled_charsmap. Each trie node
+// gets its own 256-unit block, which wastes space but keeps the double array
+// free of collisions without Darts-clone's placement search.
+func buildCharsmap(t *testing.T, rules map[string]string) []byte {
+	t.Helper()
+
+	type node struct {
+		children map[byte]*node
+		value    int // offset of the replacement, or -1
+	}
+	newNode := func() *node { return &node{children: make(map[byte]*node), value: -1} }
+
+	root := newNode()
+	var replacements []byte
+	keys := make([]string, 0, len(rules))
+	for key := range rules {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		n := root
+		for i := 0; i < len(key); i++ {
+			child, ok := n.children[key[i]]
+			if !ok {
+				child = newNode()
+				n.children[key[i]] = child
+			}
+			n = child
+		}
+		n.value = len(replacements)
+		replacements = append(replacements, rules[key]...)
+		replacements = append(replacements, 0)
+	}
+
+	var units []uint32
+	blocks := 0
+	var place func(n *node, pos uint32, label byte)
+	place = func(n *node, pos uint32, label byte) {
+		blocks++
+		base := uint32(blocks) * 256
+		if need := int(base) + 256; len(units) < need {
+			units = append(units, make([]uint32, need-len(units))...)
+		}
+
+		unit := (pos^base)<<10 | uint32(label)
+		if n.value >= 0 {
+			unit |= 1 << 8
+			units[base] = uint32(n.value) | 1<<31
+		}
+		units[pos] = unit
+
+		labels := make([]byte, 0, len(n.children))
+		for c := range n.children {
+			labels = append(labels, c)
+		}
+		slices.Sort(labels)
+		for _, c := range labels {
+			place(n.children[c], base^uint32(c), c)
+		}
+	}
+	place(root, 0, 0)
+
+	data := binary.LittleEndian.AppendUint32(nil, uint32(len(units)*4))
+	for _, u := range units {
+		data = binary.LittleEndian.AppendUint32(data, u)
+	}
+	return append(data, replacements...)
+}
+
+// testRules is a small subset of the nmt_nfkc rules, plus a composition
+// whose key is a sequence of characters.
+var testRules = map[string]string{
+	"ﬁ":       "fi",
+	"Ａ":       "A",
+	"ｂ":       "b",
+	"①":       "1",
+	"\u3000":  " ",
+	"\u00a0":  " ",
+	"\u200b":  "",
+	"e\u0301": "\u00e9",
+}
+
+func TestCharsmap_LongestPrefix(t *testing.T) {
+	cm, err := parseCharsmap(buildCharsmap(t, testRules))
+	if err != nil {
+		t.Fatalf("parseCharsmap() error = %v", err)
+	}
+
+	tests := []struct {
+		input string
+		want  string
+		n     int
+	}{
+		{"ﬁne", "fi", 3},
+		{"Ａｂ", "A", 3},
+		{"\u3000x", " ", 3},
+		{"e\u0301t", "\u00e9", 3},
+		{"\u200bx", "", 3},
+		{"e", "", 0},
+		{"et", "", 0},
+		{"x", "", 0},
+		{"", "", 0},
+	}
+
+	for _, tc := range tests {
+		got, n := cm.longestPrefix(tc.input)
+		if got != tc.want || n != tc.n {
+			t.Errorf("longestPrefix(%q) = (%q, %d), want (%q, %d)", tc.input, got, n, tc.want, tc.n)
+		}
+	}
+}
+
+func TestParseCharsmap_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"too short", []byte{1, 0}},
+		{"size past end", binary.LittleEndian.AppendUint32(nil, 64)},
+		{"unaligned size", append(binary.LittleEndian.AppendUint32(nil, 3), 0, 0, 0)},
+		{"empty trie", binary.LittleEndian.AppendUint32(nil, 0)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := parseCharsmap(tc.data); !errors.Is(err, ErrInvalidCharsmap) {
+				t.Errorf("parseCharsmap() error = %v, want ErrInvalidCharsmap", err)
+			}
+		})
+	}
+}
+
+func TestNormalizer_Charsmap(t *testing.T) {
+	norm, err := newNormalizer(&pb.NormalizerSpec{
+		PrecompiledCharsmap: proto.String(string(buildCharsmap(t, testRules))),
+	})
+	if err != nil {
+		t.Fatalf("newNormalizer() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		align    []span
+	}{
+		{"ligature", "ﬁne", "▁fine", []span{{0, 0}, {0, 3}, {3, 3}, {3, 4}, {4, 5}}},
+		{"full-width", "Ａｂ", "▁Ab", []span{{0, 0}, {0, 3}, {3, 6}}},
+		{"combining sequence", "cafe\u0301", "▁caf\u00e9", []span{{0, 0}, {0, 1}, {1, 2}, {2, 3}, {3, 6}}},
+		{"ideographic space", "a\u3000\u00a0b", "▁a▁b", []span{{0, 0}, {0, 1}, {6, 6}, {6, 7}}},
+		{"leading mapped space", "\u3000a", "▁a", []span{{3, 3}, {3, 4}}},
+		{"deleted character", "a\u200b", "▁a", []span{{0, 0}, {0, 1}}},
+		{"unmapped space", "a\tb", "▁a\tb", []span{{0, 0}, {0, 1}, {1, 2}, {2, 3}}},
+		{"invalid utf-8", "a\xff", "▁a�", []span{{0, 0}, {0, 1}, {1, 2}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, align := norm.normalize(tc.input)
+			if got != tc.expected {
+				t.Errorf("normalize(%q) = %q, want %q", tc.input, got, tc.expected)
+			}
+			if !slices.Equal(align, tc.align) {
+				t.Errorf("normalize(%q) alignment = %v, want %v", tc.input, align, tc.align)
+			}
+		})
+	}
+}
+
+func TestNormalizer_Spec(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     *pb.NormalizerSpec
+		input    string
+		expected string
+	}{
+		{"defaults", nil, "  a  b ", "▁a▁b"},
+		{"no dummy prefix", &pb.NormalizerSpec{AddDummyPrefix: proto.Bool(false)}, "  a  b ", "a▁b"},
+		{"keep whitespace", &pb.NormalizerSpec{RemoveExtraWhitespaces: proto.Bool(false)}, " a  b ", "▁▁a▁▁b▁"},
+		{"unescaped", &pb.NormalizerSpec{EscapeWhitespaces: proto.Bool(false)}, "a  b", " a b"},
+		{"all disabled", &pb.NormalizerSpec{
+			AddDummyPrefix:         proto.Bool(false),
+			RemoveExtraWhitespaces: proto.Bool(false),
+			EscapeWhitespaces:      proto.Bool(false),
+		}, " a  b", " a  b"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			norm, err := newNormalizer(tc.spec)
+			if err != nil {
+				t.Fatalf("newNormalizer() error = %v", err)
+			}
+			got, align := norm.normalize(tc.input)
+			if got != tc.expected {
+				t.Errorf("normalize(%q) = %q, want %q", tc.input, got, tc.expected)
+			}
+			if n := len([]rune(got)); n != len(align) {
+				t.Errorf("alignment has %d spans for %d normalized runes", len(align), n)
+			}
+		})
+	}
+}
+
+func TestNewFromModel_InvalidCharsmap(t *testing.T) {
+	model := &Model{NormalizerSpec: &pb.NormalizerSpec{PrecompiledCharsmap: proto.String("\x01")}}
+	if _, err := newFromModel(model); !errors.Is(err, ErrInvalidCharsmap) {
+		t.Errorf("newFromModel() error = %v, want ErrInvalidCharsmap", err)
+	}
+}
@@ -30,6 +30,12 @@ func LoadModel(path string) (*Model, error) {
 		return nil, fmt.Errorf("reading model file: %w", err)
 	}
 
+	return LoadModelFromBytes(data)
+}
+
+// LoadModelFromBytes loads a SentencePiece model from the contents of a
+// .model file.
+func LoadModelFromBytes(data []byte) (*Model, error) {
 	var modelProto pb.ModelProto
 	if err := proto.Unmarshal(data, &modelProto); err != nil {
 		return nil, fmt.Errorf("parsing protobuf: %w", err)
@@ -3,39 +3,183 @@ package tokenizer
 import (
 	"strings"
 	"unicode"
+	"unicode/utf8"
+
+	pb "github.com/jamesainslie/go-sat/internal/proto"
 )
 
 const sentencePieceSpace = '▁' // U+2581 LOWER ONE EIGHTH BLOCK
 
-// normalize prepares text for tokenization following XLM-RoBERTa conventions.
-// - Adds dummy prefix (space at start)
-// - Replaces spaces with ▁
-// - Normalizes whitespace (collapses runs, trims trailing)
-func normalize(text string) string {
+// span is a half-open byte range [start, end) in the original text.
+type span struct {
+	start int
+	end   int
+}
+
+// normalizer implements SentencePiece's normalization as configured by a
+// model's NormalizerSpec.
+type normalizer struct {
+	// charsmap holds the precompiled rules, or nil. Without rules every
+	// Unicode space is treated as a space, approximating nmt_nfkc.
+	charsmap *charsmap
+
+	addDummyPrefix         bool // prepend a space before the first character
+	removeExtraWhitespaces bool // collapse runs of spaces, trim both ends
+	escapeWhitespaces      bool // write spaces as ▁
+}
+
+// defaultNormalizer follows XLM-RoBERTa conventions without precompiled
+// rules.
+var defaultNormalizer = normalizer{
+	addDummyPrefix:         true,
+	removeExtraWhitespaces: true,
+	escapeWhitespaces:      true,
+}
+
+// newNormalizer returns the normalizer described by spec. A nil spec selects
+// the SentencePiece defaults.
+func newNormalizer(spec *pb.NormalizerSpec) (normalizer, error) {
+	n := normalizer{
+		addDummyPrefix:         spec.GetAddDummyPrefix(),
+		removeExtraWhitespaces: spec.GetRemoveExtraWhitespaces(),
+		escapeWhitespaces:      spec.GetEscapeWhitespaces(),
+	}
+	if data := spec.GetPrecompiledCharsmap(); len(data) > 0 {
+		cm, err := parseCharsmap([]byte(data))
+		if err != nil {
+			return normalizer{}, err
+		}
+		n.charsmap = cm
+	}
+	return n, nil
+}
+
+// normalize prepares text for tokenization with the default normalizer.
+func normalize(text string) (string, []span) {
+	return defaultNormalizer.normalize(text)
+}
+
+// normalize prepares text for tokenization, following SentencePiece's
+// Normalizer::Normalize:
+//   - Applies the precompiled rules, replacing the longest matching prefix
+//   - Adds a dummy prefix (space at start)
+//   - Collapses runs of spaces and trims them at both ends
+//   - Replaces spaces with ▁
+//
+// Each option can be disabled by the model's NormalizerSpec.
+//
+// It also returns an alignment with one span per rune of the normalized text,
+// giving the bytes of the original text that rune was derived from. Spaces
+// get an empty span at the start of the character they precede, so that a
+// token like "▁world" covers only "world" in the original text. When a rule
+// expands one character into several, such as "ﬁ" into "fi", the first claims
+// the original bytes and the rest get an empty span after them.
+func (n normalizer) normalize(text string) (string, []span) {
 	if text == "" {
-		return ""
+		return "", nil
 	}
 
-	// Normalize whitespace: collapse runs, trim trailing
-	var builder strings.Builder
-	needSpace := true // start true to add dummy prefix before first non-space
-
-	for _, r := range text {
-		if unicode.IsSpace(r) {
-			// Mark that we need a space before the next non-space char
-			// (only if we've already written something)
-			if builder.Len() > 0 {
-				needSpace = true
+	consumed := 0
+	if n.removeExtraWhitespaces {
+		for consumed < len(text) {
+			piece, size := n.prefix(text[consumed:])
+			if strings.Trim(piece, " ") != "" {
+				break
 			}
-		} else {
-			// Write pending space separator before this character
-			if needSpace {
-				builder.WriteRune(sentencePieceSpace)
-				needSpace = false
+			consumed += size
+		}
+	}
+	if consumed == len(text) {
+		return "", nil
+	}
+
+	space := " "
+	if n.escapeWhitespaces {
+		space = string(sentencePieceSpace)
+	}
+
+	var builder strings.Builder
+	builder.Grow(len(text) + len(space))
+	align := make([]span, 0, utf8.RuneCountInString(text)+1)
+	var isSpace []bool // per rune of the output, whether it is a space
+
+	writeSpace := func() {
+		builder.WriteString(space)
+		align = append(align, span{})
+		isSpace = append(isSpace, true)
+	}
+
+	if n.addDummyPrefix {
+		writeSpace()
+	}
+	prevSpace := n.removeExtraWhitespaces
+	for consumed < len(text) {
+		piece, size := n.prefix(text[consumed:])
+		if prevSpace {
+			piece = strings.TrimLeft(piece, " ")
+		}
+		claimed := false
+		for _, r := range piece {
+			if r == ' ' {
+				writeSpace()
+				continue
 			}
 			builder.WriteRune(r)
+			if claimed {
+				align = append(align, span{start: consumed + size, end: consumed + size})
+			} else {
+				align = append(align, span{start: consumed, end: consumed + size})
+				claimed = true
+			}
+			isSpace = append(isSpace, false)
+		}
+		if piece != "" {
+			prevSpace = strings.HasSuffix(piece, " ")
+		}
+		consumed += size
+		if !n.removeExtraWhitespaces {
+			prevSpace = false
+		}
+	}
+
+	normalized := builder.String()
+	if n.removeExtraWhitespaces {
+		for len(isSpace) > 0 && isSpace[len(isSpace)-1] {
+			normalized = normalized[:len(normalized)-len(space)]
+			align = align[:len(align)-1]
+			isSpace = isSpace[:len(isSpace)-1]
 		}
 	}
 
-	return builder.String()
+	// Anchor each space at the start of the character that follows it
+	next := len(text)
+	for i := len(align) - 1; i >= 0; i-- {
+		if isSpace[i] {
+			align[i] = span{start: next, end: next}
+		} else {
+			next = align[i].start
+		}
+	}
+
+	return normalized, align
+}
+
+// prefix returns the normalized form of the first character or rule match
+// of s and the number of bytes of s it consumes. Invalid UTF-8 becomes
+// U+FFFD, one byte at a time.
+func (n normalizer) prefix(s string) (string, int) {
+	if n.charsmap != nil {
+		if replacement, size := n.charsmap.longestPrefix(s); size > 0 {
+			return replacement, size
+		}
+	}
+
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError && size <= 1 {
+		return string(utf8.RuneError), 1
+	}
+	if n.charsmap == nil && unicode.IsSpace(r) {
+		return " ", size
+	}
+	return s[:size], size
 }
@@ -14,14 +14,49 @@ func TestNormalize(t *testing.T) {
 		{"two words", "Hello world", "▁Hello▁world"},
 		{"extra spaces", "  spaces  ", "▁spaces"},
 		{"empty string", "", ""},
+		{"no-space script", "你好。我们走吧！", "▁你好。我们走吧！"},
+		{"ideographic space", "はい。　いいえ。", "▁はい。▁いいえ。"},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			got := normalize(tc.input)
+			got, _ := normalize(tc.input)
 			if got != tc.expected {
 				t.Errorf("normalize(%q) = %q, want %q", tc.input, got, tc.expected)
 			}
 		})
 	}
 }
+
+func TestNormalize_Alignment(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []span
+	}{
+		{"simple word", "Hi", []span{{0, 0}, {0, 1}, {1, 2}}},
+		{"leading spaces", "  Hi", []span{{2, 2}, {2, 3}, {3, 4}}},
+		{"collapsed spaces", "a \t b", []span{{0, 0}, {0, 1}, {4, 4}, {4, 5}}},
+		{"multi-byte", "né €", []span{{0, 0}, {0, 1}, {1, 3}, {4, 4}, {4, 7}}},
+		{"invalid utf-8", "a\xffb", []span{{0, 0}, {0, 1}, {1, 2}, {2, 3}}},
+		{"full-width punctuation", "好。我", []span{{0, 0}, {0, 3}, {3, 6}, {6, 9}}},
+		{"ideographic space", "。　我", []span{{0, 0}, {0, 3}, {6, 6}, {6, 9}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			normalized, got := normalize(tc.input)
+			if n := len([]rune(normalized)); n != len(got) {
+				t.Fatalf("alignment has %d spans for %d normalized runes", len(got), n)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("normalize(%q) alignment = %v, want %v", tc.input, got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("span %d = %v, want %v", i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+}
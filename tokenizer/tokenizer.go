@@ -2,6 +2,8 @@ package tokenizer
 
 import (
 	"fmt"
+	"io"
+	"io/fs"
 	"strings"
 
 	pb "github.com/jamesainslie/go-sat/internal/proto"
@@ -17,9 +19,9 @@ import (
 //   - HF[3] = <unk> (SP[0])
 //   - HF[n+1] = SP[n] for n >= 3 (normal tokens shifted by 1)
 type Tokenizer struct {
-	pieces      map[string]int32   // token string -> SentencePiece index (internal use)
-	scores      map[string]float32 // token string -> log probability
-	idToPiece   []string           // SentencePiece index -> token string
+	vocab       *trie     // token string -> SentencePiece index (internal use)
+	idToPiece   []string  // SentencePiece index -> token string
+	idToScore   []float32 // SentencePiece index -> log probability
 	pieceToType map[string]pb.ModelProto_SentencePiece_Type
 
 	// HuggingFace-compatible token IDs
@@ -28,10 +30,14 @@ type Tokenizer struct {
 	eosID int32
 	unkID int32
 
-	maxTokenLen int
+	normalizer normalizer
 }
 
 // TokenInfo represents a token with its position in the original text.
+//
+// Start and End delimit the half-open byte range text[Start:End] covered by
+// the token. A leading ▁ in Text does not claim the whitespace it replaced, so
+// Start points at the first non-space character of the token.
 type TokenInfo struct {
 	ID    int32
 	Text  string
@@ -46,33 +52,71 @@ func New(modelPath string) (*Tokenizer, error) {
 		return nil, fmt.Errorf("loading model: %w", err)
 	}
 
+	return newFromModel(model)
+}
+
+// NewFromBytes loads a tokenizer from the contents of a SentencePiece .model
+// file, such as one embedded with go:embed.
+func NewFromBytes(data []byte) (*Tokenizer, error) {
+	model, err := LoadModelFromBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("loading model: %w", err)
+	}
+
+	return newFromModel(model)
+}
+
+// NewFromReader loads a tokenizer from a SentencePiece model read from r.
+func NewFromReader(r io.Reader) (*Tokenizer, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading model: %w", err)
+	}
+
+	return NewFromBytes(data)
+}
+
+// NewFromFS loads a tokenizer from the SentencePiece .model file name in fsys.
+func NewFromFS(fsys fs.FS, name string) (*Tokenizer, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("loading model: reading model file: %w", err)
+	}
+
+	return NewFromBytes(data)
+}
+
+// newFromModel builds a tokenizer from an already loaded model.
+func newFromModel(model *Model) (*Tokenizer, error) {
+	norm, err := newNormalizer(model.NormalizerSpec)
+	if err != nil {
+		return nil, fmt.Errorf("loading normalizer: %w", err)
+	}
+
 	t := &Tokenizer{
-		pieces:      make(map[string]int32),
-		scores:      make(map[string]float32),
 		idToPiece:   make([]string, len(model.Pieces)),
+		idToScore:   make([]float32, len(model.Pieces)),
 		pieceToType: make(map[string]pb.ModelProto_SentencePiece_Type),
 		// HuggingFace XLM-RoBERTa special token IDs
 		bosID: 0, // <s>
 		padID: 1, // <pad>
 		eosID: 2, // </s>
 		unkID: 3, // <unk>
+
+		normalizer: norm,
 	}
 
 	for i, piece := range model.Pieces {
 		pieceStr := piece.Piece
 
-		// Store SentencePiece index for internal Viterbi algorithm
-		t.pieces[pieceStr] = int32(i)
-		t.scores[pieceStr] = piece.Score
 		t.idToPiece[i] = pieceStr
+		t.idToScore[i] = piece.Score
 		t.pieceToType[pieceStr] = piece.Type
-
-		// Track max token length for optimization
-		if len(pieceStr) > t.maxTokenLen {
-			t.maxTokenLen = len(pieceStr)
-		}
 	}
 
+	// Index pieces by SentencePiece index for the internal Viterbi algorithm
+	t.vocab = newTrie(t.idToPiece)
+
 	return t, nil
 }
 
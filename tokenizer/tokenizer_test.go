@@ -1,9 +1,21 @@
 package tokenizer
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand/v2"
 	"os"
+	"slices"
+	"strings"
 	"testing"
+	"testing/fstest"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jamesainslie/go-sat/internal/proto"
 )
 
 func TestNew(t *testing.T) {
@@ -159,3 +171,317 @@ func TestTokenizer_Decode(t *testing.T) {
 		}
 	}
 }
+
+// newTestTokenizer builds a tokenizer from a small in-memory vocabulary so
+// offset behaviour can be tested without the full XLM-RoBERTa model.
+func newTestTokenizer(t *testing.T) *Tokenizer {
+	t.Helper()
+
+	pieces := []Piece{
+		{Piece: "<unk>", Type: pb.ModelProto_SentencePiece_UNKNOWN},
+		{Piece: "<s>", Type: pb.ModelProto_SentencePiece_CONTROL},
+		{Piece: "</s>", Type: pb.ModelProto_SentencePiece_CONTROL},
+	}
+	for _, p := range []string{"▁", "▁Hello", "▁world", "Hello", "world", ".", "!", "é", "▁né", "€", "日本", "。", "a", "b"} {
+		pieces = append(pieces, Piece{Piece: p, Score: -1, Type: pb.ModelProto_SentencePiece_NORMAL})
+	}
+	tok, err := newFromModel(&Model{Pieces: pieces})
+	if err != nil {
+		t.Fatalf("newFromModel() error = %v", err)
+	}
+	return tok
+}
+
+func TestTokenizer_Encode_Offsets(t *testing.T) {
+	tok := newTestTokenizer(t)
+
+	tests := []struct {
+		input string
+		want  [][2]int
+	}{
+		{"Hello world.", [][2]int{{0, 5}, {6, 11}, {11, 12}}},
+		{"  Hello   world", [][2]int{{2, 7}, {10, 15}}},
+		{"né €!", [][2]int{{0, 3}, {4, 4}, {4, 7}, {7, 8}}},
+		{"日本。日本", [][2]int{{0, 0}, {0, 6}, {6, 9}, {9, 15}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			tokens := tok.Encode(tc.input)
+			if len(tokens) != len(tc.want) {
+				t.Fatalf("got %d tokens %+v, want %d", len(tokens), tokens, len(tc.want))
+			}
+			for i, tk := range tokens {
+				if tk.Start != tc.want[i][0] || tk.End != tc.want[i][1] {
+					t.Errorf("token %d (%q): got [%d,%d), want [%d,%d)",
+						i, tk.Text, tk.Start, tk.End, tc.want[i][0], tc.want[i][1])
+				}
+			}
+		})
+	}
+}
+
+func TestTokenizer_Encode_OffsetsProperty(t *testing.T) {
+	tok := newTestTokenizer(t)
+	alphabet := []string{"a", "b", "é", "€", "日本", "。", " ", "  ", "\t", "\n", ".", "!", "Hello", "x", "\xff"}
+	rng := rand.New(rand.NewPCG(1, 2))
+
+	for iter := 0; iter < 500; iter++ {
+		var sb strings.Builder
+		for n := rng.IntN(20); n > 0; n-- {
+			sb.WriteString(alphabet[rng.IntN(len(alphabet))])
+		}
+		text := sb.String()
+
+		prevEnd := 0
+		for i, tk := range tok.Encode(text) {
+			if tk.Start < prevEnd || tk.End < tk.Start || tk.End > len(text) {
+				t.Fatalf("%q: token %d (%q) has invalid span [%d,%d) after %d",
+					text, i, tk.Text, tk.Start, tk.End, prevEnd)
+			}
+			// Everything skipped between tokens must be whitespace
+			if gap := text[prevEnd:tk.Start]; strings.TrimSpace(gap) != "" {
+				t.Fatalf("%q: non-space text %q skipped before token %d", text, gap, i)
+			}
+			prevEnd = tk.End
+		}
+		if tail := text[prevEnd:]; strings.TrimSpace(tail) != "" {
+			t.Fatalf("%q: non-space tail %q not covered by tokens", text, tail)
+		}
+	}
+}
+
+func TestNewFromBytes(t *testing.T) {
+	data, err := proto.Marshal(&pb.ModelProto{
+		Pieces: []*pb.ModelProto_SentencePiece{
+			{Piece: proto.String("<unk>"), Type: pb.ModelProto_SentencePiece_UNKNOWN.Enum()},
+			{Piece: proto.String("<s>"), Type: pb.ModelProto_SentencePiece_CONTROL.Enum()},
+			{Piece: proto.String("</s>"), Type: pb.ModelProto_SentencePiece_CONTROL.Enum()},
+			{Piece: proto.String("▁Hello"), Score: proto.Float32(-1)},
+			{Piece: proto.String("▁world"), Score: proto.Float32(-1)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("marshal model: %v", err)
+	}
+	want := []int32{4, 5}
+
+	loaders := map[string]func() (*Tokenizer, error){
+		"bytes":  func() (*Tokenizer, error) { return NewFromBytes(data) },
+		"reader": func() (*Tokenizer, error) { return NewFromReader(bytes.NewReader(data)) },
+		"fs": func() (*Tokenizer, error) {
+			return NewFromFS(fstest.MapFS{"models/sp.model": {Data: data}}, "models/sp.model")
+		},
+	}
+	for name, load := range loaders {
+		t.Run(name, func(t *testing.T) {
+			tok, err := load()
+			if err != nil {
+				t.Fatalf("load failed: %v", err)
+			}
+			if got := tok.EncodeIDs("Hello world"); !slices.Equal(got, want) {
+				t.Errorf("EncodeIDs = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestNewFromFS_FileNotFound(t *testing.T) {
+	_, err := NewFromFS(fstest.MapFS{}, "missing.model")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist, got: %v", err)
+	}
+}
+
+func TestNewFromBytes_InvalidProtobuf(t *testing.T) {
+	if _, err := NewFromBytes([]byte{0xff, 0xff, 0xff}); err == nil {
+		t.Error("expected error for invalid protobuf")
+	}
+}
+
+// referenceEncoder is the candidate substring search that Encode replaced,
+// trying every substring up to the longest piece against a map. It is the
+// oracle for TestTokenizer_Encode_MatchesReference and the baseline for
+// BenchmarkTokenizer_Encode.
+type referenceEncoder struct {
+	t           *Tokenizer
+	pieces      map[string]int32
+	maxTokenLen int
+}
+
+func newReferenceEncoder(t *Tokenizer) *referenceEncoder {
+	r := &referenceEncoder{t: t, pieces: make(map[string]int32, len(t.idToPiece))}
+	for i, p := range t.idToPiece {
+		r.pieces[p] = int32(i)
+		r.maxTokenLen = max(r.maxTokenLen, len(p))
+	}
+	return r
+}
+
+func (r *referenceEncoder) encode(text string) []TokenInfo {
+	t, pieces, maxTokenLen := r.t, r.pieces, r.maxTokenLen
+	normalized, align := t.normalizer.normalize(text)
+	if normalized == "" {
+		return nil
+	}
+
+	runes := []rune(normalized)
+	n := len(runes)
+	best := make([]float64, n+1)
+	parent := make([]int, n+1)
+	tokenAt := make([]string, n+1)
+	for i := 1; i <= n; i++ {
+		best[i] = negInf
+		for length := 1; length <= min(maxTokenLen, i); length++ {
+			substr := string(runes[i-length : i])
+			spIndex, ok := pieces[substr]
+			if !ok {
+				continue
+			}
+			if candidate := best[i-length] + float64(t.idToScore[spIndex]); candidate > best[i] {
+				best[i], parent[i], tokenAt[i] = candidate, i-length, substr
+			}
+		}
+		if best[i] == negInf {
+			best[i] = best[i-1] + float64(t.idToScore[0])
+			parent[i], tokenAt[i] = i-1, string(runes[i-1:i])
+		}
+	}
+
+	var tokens []TokenInfo
+	for pos := n; pos > 0; pos = parent[pos] {
+		spIndex := pieces[tokenAt[pos]]
+		tokens = append(tokens, TokenInfo{
+			ID:    t.spIndexToHFID(spIndex),
+			Text:  tokenAt[pos],
+			Start: align[parent[pos]].start,
+			End:   align[pos-1].end,
+		})
+	}
+	slices.Reverse(tokens)
+	return tokens
+}
+
+// benchWords are the words of the synthetic vocabulary and texts.
+var benchWords = strings.Fields(`the of and to in is was for on that with as by at
+from his her it an were are which this be or has had first one their its new after
+but who not they have two been other when there all during into school time may
+years more most only over city some world would where later up such used many can
+state about national out known university united then made segmentation sentence
+naïve café résumé 日本 東京 中国 北京 こんにちは ありがとう 🎉 👍🏽`)
+
+// newBenchTokenizer builds a tokenizer over a synthetic vocabulary of about
+// 20,000 pieces: characters, words and random fragments of words, with and
+// without ▁, scored so that longer pieces are likelier.
+func newBenchTokenizer(tb testing.TB) *Tokenizer {
+	tb.Helper()
+
+	rng := rand.New(rand.NewPCG(3, 4))
+	pieces := []Piece{
+		{Piece: "<unk>", Type: pb.ModelProto_SentencePiece_UNKNOWN},
+		{Piece: "<s>", Type: pb.ModelProto_SentencePiece_CONTROL},
+		{Piece: "</s>", Type: pb.ModelProto_SentencePiece_CONTROL},
+	}
+	seen := make(map[string]bool)
+	add := func(p string) {
+		if p == "" || seen[p] {
+			return
+		}
+		seen[p] = true
+		score := -10 + float32(len(p))/2 - rng.Float32()
+		pieces = append(pieces, Piece{Piece: p, Score: score, Type: pb.ModelProto_SentencePiece_NORMAL})
+	}
+
+	add("▁")
+	for _, w := range benchWords {
+		for _, r := range w {
+			add(string(r))
+		}
+		add("▁" + w)
+	}
+	for len(pieces) < 20000 {
+		runes := []rune(benchWords[rng.IntN(len(benchWords))])
+		start := rng.IntN(len(runes))
+		fragment := string(runes[start : start+1+rng.IntN(len(runes)-start)])
+		if rng.IntN(2) == 0 {
+			fragment = "▁" + fragment
+		}
+		add(fragment + string(rune('a'+rng.IntN(26))))
+		add(fragment)
+	}
+
+	tok, err := newFromModel(&Model{Pieces: pieces})
+	if err != nil {
+		tb.Fatalf("newFromModel() error = %v", err)
+	}
+	return tok
+}
+
+// benchText returns about size bytes of words from benchWords, with the
+// occasional character the vocabulary lacks.
+func benchText(rng *rand.Rand, size int) string {
+	var sb strings.Builder
+	for sb.Len() < size {
+		sb.WriteString(benchWords[rng.IntN(len(benchWords))])
+		switch rng.IntN(20) {
+		case 0:
+			sb.WriteString(". ")
+		case 1:
+			sb.WriteString("Ж")
+		default:
+			sb.WriteString(" ")
+		}
+	}
+	return sb.String()
+}
+
+func TestTokenizer_Encode_MatchesReference(t *testing.T) {
+	tok := newBenchTokenizer(t)
+	ref := newReferenceEncoder(tok)
+	rng := rand.New(rand.NewPCG(5, 6))
+
+	for iter := 0; iter < 200; iter++ {
+		text := benchText(rng, rng.IntN(200))
+		got, want := tok.Encode(text), ref.encode(text)
+		if !slices.Equal(got, want) {
+			t.Fatalf("Encode(%q) =\n%v\nwant\n%v", text, got, want)
+		}
+	}
+}
+
+func BenchmarkTokenizer_Encode(b *testing.B) {
+	tok := newBenchTokenizer(b)
+	encoders := []struct {
+		name   string
+		encode func(string) []TokenInfo
+	}{
+		{"trie", tok.Encode},
+		{"reference", newReferenceEncoder(tok).encode},
+	}
+
+	for _, size := range []int{1 << 10, 1 << 14} {
+		text := benchText(rand.New(rand.NewPCG(7, 8)), size)
+		for _, enc := range encoders {
+			b.Run(fmt.Sprintf("%s/%dB", enc.name, size), func(b *testing.B) {
+				b.ReportAllocs()
+				b.SetBytes(int64(len(text)))
+				for b.Loop() {
+					enc.encode(text)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkNewFromModel(b *testing.B) {
+	model, err := LoadModel("../testdata/sentencepiece.bpe.model")
+	if err != nil {
+		b.Skipf("model not available: %v", err)
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := newFromModel(model); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package tokenizer
+
+import (
+	"cmp"
+	"slices"
+)
+
+// trieNode is a node of a vocabulary trie. The children of a node are
+// stored contiguously in label order at nodes[first : first+count].
+type trieNode struct {
+	first uint32
+	piece int32 // SentencePiece index of the piece ending here, or -1
+	count uint16
+	label byte
+}
+
+// trie indexes the vocabulary by the bytes of each piece, so the pieces
+// starting at a position of the normalized text are found in one walk,
+// without building candidate substrings.
+type trie struct {
+	nodes []trieNode // nodes[0] is the root
+}
+
+// newTrie builds a trie over pieces, indexed by position. Empty pieces are
+// left out. When a piece occurs twice the later index wins.
+func newTrie(pieces []string) *trie {
+	order := make([]int32, 0, len(pieces))
+	for i, p := range pieces {
+		if p != "" {
+			order = append(order, int32(i))
+		}
+	}
+	slices.SortStableFunc(order, func(a, b int32) int {
+		return cmp.Compare(pieces[a], pieces[b])
+	})
+
+	// Breadth first, so that the children of each node are appended
+	// together. Each queue entry is a node and the range of order sharing
+	// its prefix of the given depth.
+	type pending struct {
+		node       uint32
+		start, end int
+		depth      int
+	}
+	t := &trie{nodes: []trieNode{{piece: -1}}}
+	queue := []pending{{node: 0, start: 0, end: len(order)}}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+
+		// A piece equal to the prefix sorts before its extensions
+		lo := p.start
+		for lo < p.end && len(pieces[order[lo]]) == p.depth {
+			t.nodes[p.node].piece = order[lo]
+			lo++
+		}
+
+		t.nodes[p.node].first = uint32(len(t.nodes))
+		for lo < p.end {
+			label := pieces[order[lo]][p.depth]
+			hi := lo + 1
+			for hi < p.end && pieces[order[hi]][p.depth] == label {
+				hi++
+			}
+			queue = append(queue, pending{node: uint32(len(t.nodes)), start: lo, end: hi, depth: p.depth + 1})
+			t.nodes = append(t.nodes, trieNode{piece: -1, label: label})
+			t.nodes[p.node].count++
+			lo = hi
+		}
+	}
+
+	return t
+}
+
+// child returns the index of the child of node labelled c, or -1.
+func (t *trie) child(node int, c byte) int {
+	n := t.nodes[node]
+	children := t.nodes[n.first : n.first+uint32(n.count)]
+	lo, hi := 0, len(children)
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if children[mid].label < c {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	if lo == len(children) || children[lo].label != c {
+		return -1
+	}
+	return int(n.first) + lo
+}
@@ -0,0 +1,52 @@
+package tokenizer
+
+import (
+	"testing"
+)
+
+// lookup returns the piece index the trie holds for s, or -1.
+func (t *trie) lookup(s string) int32 {
+	node := 0
+	for i := 0; i < len(s); i++ {
+		if node = t.child(node, s[i]); node < 0 {
+			return -1
+		}
+	}
+	return t.nodes[node].piece
+}
+
+func TestTrie(t *testing.T) {
+	pieces := []string{"<unk>", "▁", "▁a", "▁ab", "ab", "", "b", "日本", "日", "ab"}
+	vocab := newTrie(pieces)
+
+	tests := []struct {
+		input string
+		want  int32
+	}{
+		{"<unk>", 0},
+		{"▁", 1},
+		{"▁a", 2},
+		{"▁ab", 3},
+		{"ab", 9}, // the later duplicate wins
+		{"b", 6},
+		{"日本", 7},
+		{"日", 8},
+		{"", -1},
+		{"a", -1},
+		{"▁abc", -1},
+		{"\xe6", -1}, // first byte of 日
+	}
+
+	for _, tc := range tests {
+		if got := vocab.lookup(tc.input); got != tc.want {
+			t.Errorf("lookup(%q) = %d, want %d", tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestTrie_Empty(t *testing.T) {
+	vocab := newTrie(nil)
+	if got := vocab.lookup("a"); got != -1 {
+		t.Errorf("lookup(%q) = %d, want -1", "a", got)
+	}
+}
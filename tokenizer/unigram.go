@@ -1,5 +1,7 @@
 package tokenizer
 
+import "unicode/utf8"
+
 const negInf = -1e9
 
 // EncodeIDs returns HuggingFace-compatible token IDs for the input text.
@@ -12,95 +14,94 @@ func (t *Tokenizer) EncodeIDs(text string) []int32 {
 	return ids
 }
 
+// lattice is the state of the Viterbi search at a byte offset of the
+// normalized text.
+type lattice struct {
+	best   float64 // best log probability to tokenize the text before it
+	parent int32   // start of the token ending here
+	piece  int32   // SentencePiece index of that token, or -1 for <unk>
+}
+
 // Encode tokenizes text using Viterbi algorithm, returning tokens with offsets.
+//
+// The search walks the vocabulary trie from each character of the normalized
+// text, so every piece starting there is scored in one pass, and it allocates
+// no candidate strings.
 func (t *Tokenizer) Encode(text string) []TokenInfo {
 	if text == "" {
 		return nil
 	}
 
 	// Normalize text (add ▁ prefix, replace spaces)
-	normalized := normalize(text)
+	normalized, align := t.normalizer.normalize(text)
 	if normalized == "" {
 		return nil
 	}
 
-	runes := []rune(normalized)
-	n := len(runes)
-
-	// best[i] = best log probability to tokenize runes[0:i]
-	best := make([]float64, n+1)
-	// parent[i] = start position of the token ending at position i
-	parent := make([]int, n+1)
-	// tokenAt[i] = the token string ending at position i
-	tokenAt := make([]string, n+1)
-
+	n := len(normalized)
+	nodes := make([]lattice, n+1)
 	for i := 1; i <= n; i++ {
-		best[i] = negInf
-		parent[i] = -1
+		nodes[i] = lattice{best: negInf, parent: -1, piece: -1}
 	}
-
-	// Dynamic programming: find best tokenization
-	for i := 1; i <= n; i++ {
-		// Try all possible tokens ending at position i
-		maxLen := t.maxTokenLen
-		if maxLen > i {
-			maxLen = i
+	// Use <unk> token score (convert HF ID to SentencePiece index for lookup)
+	unkScore := float64(t.idToScore[t.hfIDToSPIndex(t.unkID)])
+
+	// Dynamic programming: find best tokenization. Pieces are whole
+	// characters, so only character boundaries are reached.
+	prev := 0
+	for j := 0; j <= n; {
+		// If no valid token ends here, use unknown token for the last character
+		if j > 0 && nodes[j].best == negInf {
+			nodes[j] = lattice{best: nodes[prev].best + unkScore, parent: int32(prev), piece: -1}
+		}
+		if j == n {
+			break
 		}
 
-		for length := 1; length <= maxLen; length++ {
-			j := i - length
-			substr := string(runes[j:i])
-
-			score, exists := t.scores[substr]
-			if !exists {
+		// Try all tokens starting at j. On equal scores the shortest token
+		// ending at a position wins, as it is tried last.
+		node := 0
+		for i := j; i < n; i++ {
+			if node = t.vocab.child(node, normalized[i]); node < 0 {
+				break
+			}
+			piece := t.vocab.nodes[node].piece
+			if piece < 0 {
 				continue
 			}
-
-			candidate := best[j] + float64(score)
-			if candidate > best[i] {
-				best[i] = candidate
-				parent[i] = j
-				tokenAt[i] = substr
+			candidate := nodes[j].best + float64(t.idToScore[piece])
+			if candidate >= nodes[i+1].best {
+				nodes[i+1] = lattice{best: candidate, parent: int32(j), piece: piece}
 			}
 		}
 
-		// If no valid token found, use unknown token for single character
-		if best[i] == negInf {
-			// Use <unk> token score (convert HF ID to SentencePiece index for lookup)
-			unkSPIndex := t.hfIDToSPIndex(t.unkID)
-			best[i] = best[i-1] + float64(t.scores[t.idToPiece[unkSPIndex]])
-			parent[i] = i - 1
-			tokenAt[i] = string(runes[i-1 : i])
-		}
+		_, size := utf8.DecodeRuneInString(normalized[j:])
+		prev, j = j, j+size
 	}
 
-	// Backtrack to get tokens
-	var tokens []TokenInfo
-	pos := n
-	for pos > 0 {
-		start := parent[pos]
-		tokenStr := tokenAt[pos]
-
-		spIndex, ok := t.pieces[tokenStr]
-		if !ok {
-			spIndex = 0 // <unk> is at SentencePiece index 0
-		}
-
-		// Convert SentencePiece index to HuggingFace ID
-		hfID := t.spIndexToHFID(spIndex)
-
-		tokens = append(tokens, TokenInfo{
-			ID:    hfID,
-			Text:  tokenStr,
-			Start: start,
-			End:   pos,
-		})
-		pos = start
+	// Backtrack to get tokens, filling them in from the end
+	count := 0
+	for pos := n; pos > 0; pos = int(nodes[pos].parent) {
+		count++
 	}
-
-	// Reverse to get correct order
-	for i, j := 0, len(tokens)-1; i < j; i, j = i+1, j-1 {
-		tokens[i], tokens[j] = tokens[j], tokens[i]
+	tokens := make([]TokenInfo, count)
+	pos, r := n, len(align)
+	for k := count - 1; k >= 0; k-- {
+		start := int(nodes[pos].parent)
+		tokenStr := normalized[start:pos]
+
+		// <unk> is at SentencePiece index 0
+		spIndex := max(nodes[pos].piece, 0)
+
+		// Map normalized rune positions back to original byte offsets
+		startRune := r - utf8.RuneCountInString(tokenStr)
+		tokens[k] = TokenInfo{
+			ID:    t.spIndexToHFID(spIndex),
+			Text:  tokenStr,
+			Start: align[startRune].start,
+			End:   align[r-1].end,
+		}
+		pos, r = start, startRune
 	}
 
 	return tokens